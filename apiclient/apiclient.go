@@ -4,12 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/telemetry"
 	"github.com/Kinveil/Riot-API-Golang/constants/continent"
 	"github.com/Kinveil/Riot-API-Golang/constants/league/rank"
 	"github.com/Kinveil/Riot-API-Golang/constants/league/tier"
@@ -24,6 +25,50 @@ type Client interface {
 	SetAPIKey(apiKey string)
 	SetMaxRetries(maxRetries int)
 	WithContext(ctx context.Context) Client
+	WithPriority(priority ratelimiter.Priority) Client
+
+	// WithAPIKey returns a Client whose calls are pinned to the named key in the key pool.
+	// Encrypted IDs are scoped to the key that returned them, so calls that use them
+	// must be sent with the same key.
+	WithAPIKey(name string) Client
+
+	// WithNonBlocking returns a Client whose calls fail with ratelimiter.ErrWouldBlock,
+	// which holds the estimated wait, instead of waiting on a rate limit.
+	WithNonBlocking() Client
+
+	// WithRetryPolicy returns a Client whose calls are retried according to the given policy,
+	// instead of the policy set with the WithRetryPolicy option.
+	WithRetryPolicy(policy ratelimiter.RetryPolicy) Client
+
+	// WithResponseInfo returns a Client whose calls fill info with the metadata of their response,
+	// including how long they waited for rate limits. Each call overwrites info, so the returned
+	// Client must not be used by several goroutines at once.
+	WithResponseInfo(info *ResponseInfo) Client
+
+	// WithCacheBypass returns a Client whose calls are sent to the Riot API even if their response
+	// is cached. Their responses are still cached.
+	WithCacheBypass() Client
+
+	// WithStaleWhileRevalidate returns a Client whose calls return cached responses that are no
+	// longer fresh, as long as they are within the cache's MaxStale, and refresh them in the background.
+	WithStaleWhileRevalidate() Client
+
+	// Stats returns a snapshot of the rate limiter's state for each region and method.
+	Stats() (ratelimiter.Stats, error)
+
+	// Estimate estimates how long it would take to make n more calls to the method in the region
+	// or continent, given the rate limits learned so far and the calls already waiting.
+	Estimate(regionOrContinent HostProvider, methodID ratelimiter.MethodID, n int) (ratelimiter.Estimate, error)
+
+	// Pending returns the number of calls that are queued, in flight or waiting to be retried for
+	// each key in the key pool, by name. If no key pool is set, the calls are counted under the
+	// empty name, as are calls that are not pinned to a key until one is selected for them.
+	Pending() map[string]int
+
+	// Close stops accepting new calls and waits for the pending calls to finish.
+	// If ctx is done first, the pending calls fail with ratelimiter.ErrClosed.
+	// Calls made after Close return ratelimiter.ErrClosed.
+	Close(ctx context.Context) error
 
 	// Account API
 
@@ -98,28 +143,107 @@ type Client interface {
 
 // client is the internal implementation of Client.
 type client struct {
-	ratelimiter *ratelimiter.RateLimiter
-	ctx         context.Context
+	ratelimiter  *ratelimiter.RateLimiter
+	hostResolver HostResolver
+	timeout      time.Duration
+	tracer       telemetry.Tracer
+	ctx          context.Context
+	priority     ratelimiter.Priority
+	apiKeyName   string
+	nonBlocking  bool
+	retryPolicy  ratelimiter.RetryPolicy
+	responseInfo *ResponseInfo
+	coalescer    *coalescer
+
+	cache                *responseCache
+	cacheBypass          bool
+	staleWhileRevalidate bool
+
+	middlewares []Middleware
 }
 
-// New returns a Client configured for the given API client and underlying HTTP
-// client. The returned Client is threadsafe.
-func New(apiKey string) Client {
+// New returns a Client configured for the given API key and options.
+// The returned Client is threadsafe.
+func New(apiKey string, opts ...Option) Client {
 	requests := make(chan *ratelimiter.APIRequest)
 
-	ratelimiter := ratelimiter.NewRateLimiter(requests, apiKey)
-	go ratelimiter.Start()
+	c := &client{
+		ratelimiter: ratelimiter.NewRateLimiter(requests, apiKey),
+	}
 
-	return &client{
-		ratelimiter: ratelimiter,
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	go c.ratelimiter.Start()
+
+	return c
 }
 
 func (c *client) WithContext(ctx context.Context) Client {
-	return &client{
-		ratelimiter: c.ratelimiter,
-		ctx:         ctx,
-	}
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+// WithPriority returns a Client whose requests are queued with the given priority.
+// When a rate limit slot frees up, requests with a higher priority are served first.
+func (c *client) WithPriority(priority ratelimiter.Priority) Client {
+	cc := *c
+	cc.priority = priority
+	return &cc
+}
+
+func (c *client) WithAPIKey(name string) Client {
+	cc := *c
+	cc.apiKeyName = name
+	return &cc
+}
+
+func (c *client) WithNonBlocking() Client {
+	cc := *c
+	cc.nonBlocking = true
+	return &cc
+}
+
+func (c *client) WithRetryPolicy(policy ratelimiter.RetryPolicy) Client {
+	cc := *c
+	cc.retryPolicy = policy
+	return &cc
+}
+
+func (c *client) WithResponseInfo(info *ResponseInfo) Client {
+	cc := *c
+	cc.responseInfo = info
+	return &cc
+}
+
+func (c *client) WithCacheBypass() Client {
+	cc := *c
+	cc.cacheBypass = true
+	return &cc
+}
+
+func (c *client) WithStaleWhileRevalidate() Client {
+	cc := *c
+	cc.staleWhileRevalidate = true
+	return &cc
+}
+
+func (c *client) Stats() (ratelimiter.Stats, error) {
+	return c.ratelimiter.Stats()
+}
+
+func (c *client) Estimate(regionOrContinent HostProvider, methodID ratelimiter.MethodID, n int) (ratelimiter.Estimate, error) {
+	return c.ratelimiter.Estimate(strings.ToUpper(regionOrContinent.String()), methodID, n)
+}
+
+func (c *client) Pending() map[string]int {
+	return c.ratelimiter.Pending()
+}
+
+func (c *client) Close(ctx context.Context) error {
+	return c.ratelimiter.Close(ctx)
 }
 
 func (c *client) SetUsageConservation(conserveUsage ratelimiter.ConserveUsage) {
@@ -139,7 +263,7 @@ type HostProvider interface {
 	String() string
 }
 
-func (c *client) dispatchAndUnmarshal(regionOrContinent HostProvider, method string, relativePath string, parameters url.Values, methodID ratelimiter.MethodID, dest interface{}) (*http.Response, error) {
+func (c *client) dispatchAndUnmarshal(regionOrContinent HostProvider, method string, relativePath string, parameters url.Values, methodID ratelimiter.MethodID, dest interface{}) (*Response, error) {
 	var suffix, separator string
 
 	if len(parameters) > 0 {
@@ -150,39 +274,188 @@ func (c *client) dispatchAndUnmarshal(regionOrContinent HostProvider, method str
 		separator = "/"
 	}
 
-	URL := regionOrContinent.Host() + method + separator + relativePath + suffix
+	host := regionOrContinent.Host()
+	if c.hostResolver != nil {
+		host = c.hostResolver(regionOrContinent)
+	}
+
+	URL := host + method + separator + relativePath + suffix
+
+	send := func(c *client, dest interface{}) (*Response, error) {
+		if c.coalescer != nil && c.coalescer.coalesces(methodID) {
+			// Calls pinned to different keys must not share results, since encrypted IDs are key-scoped
+			return c.coalescer.do(c.ctx, c.apiKeyName+" "+URL, dest, c.responseInfo, func(dest interface{}, info *ResponseInfo) (*Response, error) {
+				return c.dispatch(regionOrContinent, URL, methodID, dest, info)
+			})
+		}
+
+		return c.dispatch(regionOrContinent, URL, methodID, dest, c.responseInfo)
+	}
+
+	if c.cache != nil {
+		if ttl, ok := c.cache.ttls[methodID]; ok {
+			return c.dispatchCached(regionOrContinent, URL, methodID, ttl, dest, send)
+		}
+	}
+
+	return send(c, dest)
+}
+
+// dispatch sends a request for the URL through the rate limiter and unmarshals the response body into dest.
+// If info is not nil, it is filled with the metadata of the response.
+func (c *client) dispatch(regionOrContinent HostProvider, URL string, methodID ratelimiter.MethodID, dest interface{}, info *ResponseInfo) (_ *Response, err error) {
+	ctx := c.ctx
+	if c.timeout > 0 {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+		}
+	}
+
+	var span telemetry.Span
+	if c.tracer != nil {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		ctx, span = c.tracer.Start(ctx, telemetry.SpanCall,
+			telemetry.Attribute{Key: telemetry.AttrRegion, Value: strings.ToUpper(regionOrContinent.String())},
+			telemetry.Attribute{Key: telemetry.AttrMethodID, Value: methodID.String()},
+			telemetry.Attribute{Key: telemetry.AttrURL, Value: URL},
+		)
+
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+			}
+
+			span.End()
+		}()
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
 
-	responseChan := make(chan *http.Response)
 	newRequest := ratelimiter.APIRequest{
-		Context:  c.ctx,
-		Region:   strings.ToUpper(regionOrContinent.String()),
+		Region:      strings.ToUpper(regionOrContinent.String()),
+		MethodID:    methodID,
+		URL:         URL,
+		Priority:    c.priority,
+		Key:         c.apiKeyName,
+		NonBlocking: c.nonBlocking,
+		RetryPolicy: c.retryPolicy,
+	}
+
+	call := &Call{
+		Context:  ctx,
+		Region:   newRequest.Region,
 		MethodID: methodID,
 		URL:      URL,
-		Response: responseChan,
+		Header:   make(http.Header),
 	}
 
-	c.ratelimiter.Requests <- &newRequest
-	response := <-responseChan
+	start := time.Now()
+	response, err := chain(c.middlewares, c.send(&newRequest))(call)
+	if info != nil {
+		*info = newResponseInfo(&newRequest, response, time.Since(start))
+	}
 
+	if err != nil {
+		return nil, err
+	}
+
+	// A middleware that returns neither a response nor an error is a bug, reported as an unknown error
 	if response == nil {
-		return nil, ErrUnknown
+		return nil, newRequestError(&newRequest, ErrUnknown)
 	}
 
-	defer response.Body.Close()
+	// The body's buffer is reused once it is decoded
+	defer response.release()
+
+	if span != nil {
+		span.SetAttributes(
+			telemetry.Attribute{Key: telemetry.AttrStatus, Value: response.StatusCode},
+			telemetry.Attribute{Key: telemetry.AttrRetries, Value: newRequest.Retries},
+		)
+	}
 
 	if response.StatusCode != http.StatusOK {
-		if err, ok := StatusToError[response.StatusCode]; ok {
-			return nil, err
+		if response.stream != nil {
+			if err := response.readStream(maxErrorBodySize); err != nil {
+				return nil, newRequestError(&newRequest, err)
+			}
 		}
 
-		return nil, ErrUnknown
+		return nil, newResponseError(&newRequest, response)
 	}
 
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, err
+	if response.stream != nil {
+		body := &readErrorRecorder{Reader: response.stream}
+		err := decodeStream(body, dest)
+		if body.err != nil {
+			return nil, newRequestError(&newRequest, body.err)
+		}
+
+		return response, err
 	}
 
-	err = json.Unmarshal(body, dest)
-	return response, err
+	return response, json.Unmarshal(response.Body, dest)
+}
+
+// send returns the Handler that sends a call through the rate limiter with the settings of req,
+// and reads the response's body. req is updated with the call and the rate limiter's details.
+//
+// Middlewares are given the body as bytes, so it is only read when there are middlewares.
+// Otherwise, dispatch decodes it as it is read.
+func (c *client) send(req *ratelimiter.APIRequest) Handler {
+	return func(call *Call) (*Response, error) {
+		responseChan := make(chan *ratelimiter.APIResponse, 1)
+		req.Context = call.Context
+		req.Region = call.Region
+		req.MethodID = call.MethodID
+		req.URL = call.URL
+		req.Header = call.Header
+		req.Response = responseChan
+
+		if err := c.ratelimiter.Submit(req); err != nil {
+			return nil, newRequestError(req, err)
+		}
+
+		result := <-responseChan
+		if result.Err != nil {
+			return nil, newRequestError(req, result.Err)
+		}
+
+		if result.Response == nil {
+			return nil, newRequestError(req, ErrUnknown)
+		}
+
+		if len(c.middlewares) == 0 {
+			return &Response{
+				StatusCode: result.Response.StatusCode,
+				Header:     result.Response.Header,
+				stream:     result.Response.Body,
+			}, nil
+		}
+
+		defer result.Response.Body.Close()
+
+		buffer, err := readBody(result.Response.Body, result.Response.ContentLength)
+		if err != nil {
+			return nil, newRequestError(req, err)
+		}
+
+		return &Response{
+			StatusCode: result.Response.StatusCode,
+			Header:     result.Response.Header,
+			Body:       buffer.Bytes(),
+			buffer:     buffer,
+		}, nil
+	}
 }
@@ -0,0 +1,127 @@
+package apiclienttest
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+)
+
+// route is the path of a method of the Riot API. "{}" segments are path parameters.
+type route struct {
+	methodID ratelimiter.MethodID
+	segments []string
+}
+
+// routes are the paths of every method called by apiclient.Client.
+//
+// GetSummonerByRsoPuuid is sent to the same path as GetSummonerByAccountID, so its requests
+// are handled as GetSummonerByAccountID.
+var routes = newRoutes(map[string]ratelimiter.MethodID{
+	"/riot/account/v1/accounts/by-puuid/{}":      ratelimiter.GetAccountByPuuid,
+	"/riot/account/v1/accounts/by-riot-id/{}/{}": ratelimiter.GetAccountByRiotID,
+
+	"/lol/platform/v3/champion-rotations": ratelimiter.GetChampionRotations,
+
+	"/lol/champion-mastery/v4/champion-masteries/by-summoner/{}":                ratelimiter.GetChampionMasteriesBySummonerID,
+	"/lol/champion-mastery/v4/champion-masteries/by-summoner/{}/by-champion/{}": ratelimiter.GetChampionMasteryBySummonerIDAndChampionID,
+	"/lol/champion-mastery/v4/champion-masteries/by-summoner/{}/top":            ratelimiter.GetChampionMasteriesTopBySummonerID,
+	"/lol/champion-mastery/v4/scores/by-summoner/{}":                            ratelimiter.GetChampionMasteryScoreTotalBySummonerID,
+
+	"/lol/clash/v1/players/by-puuid/{}":             ratelimiter.GetClashPlayersByPuuid,
+	"/lol/clash/v1/players/by-summoner/{}":          ratelimiter.GetClashPlayersBySummonerID,
+	"/lol/clash/v1/teams/{}":                        ratelimiter.GetClashTeamByID,
+	"/lol/clash/v1/tournaments":                     ratelimiter.GetClashTournaments,
+	"/lol/clash/v1/tournaments/by-team/{}":          ratelimiter.GetClashTournamentByTeamID,
+	"/lol/clash/v1/tournaments/{}":                  ratelimiter.GetClashTournamentByID,
+	"/lol/league/v4/challengerleagues/by-queue/{}":  ratelimiter.GetLeagueEntriesChallenger,
+	"/lol/league/v4/grandmasterleagues/by-queue/{}": ratelimiter.GetLeagueEntriesGrandmaster,
+	"/lol/league/v4/masterleagues/by-queue/{}":      ratelimiter.GetLeagueEntriesMaster,
+	"/lol/league/v4/entries/by-summoner/{}":         ratelimiter.GetLeagueEntriesBySummonerID,
+	"/lol/league/v4/entries/{}/{}/{}":               ratelimiter.GetLeagueEntries,
+	"/lol/league/v4/leagues/{}":                     ratelimiter.GetLeagueEntriesByID,
+	"/lol/league-exp/v4/entries/{}/{}/{}":           ratelimiter.GetLeagueExpEntries,
+
+	"/lol/challenges/v1/config":                     ratelimiter.GetChallengesConfig,
+	"/lol/challenges/v1/config/{}":                  ratelimiter.GetChallengesConfigByID,
+	"/lol/challenges/v1/percentiles":                ratelimiter.GetChallengesPercentiles,
+	"/lol/challenges/v1/percentiles/{}":             ratelimiter.GetChallengesPercentilesByID,
+	"/lol/challenges/v1/leaderboards/{}/{}":         ratelimiter.GetChallengesLeaderboardsByLevel,
+	"/lol/challenges/v1/player-data/{}":             ratelimiter.GetChallengesPlayerDataByPuuid,
+	"/lol/status/v4/platform-data":                  ratelimiter.GetStatusPlatformData,
+	"/lol/match/v5/matches/by-puuid/{}/ids":         ratelimiter.GetMatchlist,
+	"/lol/match/v5/matches/{}":                      ratelimiter.GetMatch,
+	"/lol/match/v5/matches/{}/timeline":             ratelimiter.GetMatchTimeline,
+	"/lol/spectator/v4/active-games/by-summoner/{}": ratelimiter.GetSpectatorActiveGameBySummonerID,
+	"/lol/spectator/v4/featured-games":              ratelimiter.GetSpectatorFeaturedGames,
+
+	"/lol/summoner/v4/summoners/by-account/{}": ratelimiter.GetSummonerByAccountID,
+	"/lol/summoner/v4/summoners/by-name/{}":    ratelimiter.GetSummonerByName,
+	"/lol/summoner/v4/summoners/by-puuid/{}":   ratelimiter.GetSummonerByPuuid,
+	"/lol/summoner/v4/summoners/{}":            ratelimiter.GetSummonerBySummonerID,
+})
+
+func newRoutes(paths map[string]ratelimiter.MethodID) []route {
+	var routes []route
+	for path, methodID := range paths {
+		routes = append(routes, route{
+			methodID: methodID,
+			segments: strings.Split(strings.TrimPrefix(path, "/"), "/"),
+		})
+	}
+
+	return routes
+}
+
+// match returns the method and path parameters of a path, without its region prefix.
+// If several routes match, the one with the most literal segments is used. A trailing
+// slash is ignored, since the client adds one to methods without path parameters.
+func match(escapedPath string) (ratelimiter.MethodID, []string, bool) {
+	segments := strings.Split(strings.Trim(escapedPath, "/"), "/")
+
+	var best *route
+	var bestLiterals int
+	for i := range routes {
+		r := &routes[i]
+		if len(r.segments) != len(segments) {
+			continue
+		}
+
+		literals, ok := 0, true
+		for j, segment := range r.segments {
+			if segment == "{}" {
+				continue
+			}
+
+			if segment != segments[j] {
+				ok = false
+				break
+			}
+
+			literals++
+		}
+
+		if ok && (best == nil || literals > bestLiterals) {
+			best = r
+			bestLiterals = literals
+		}
+	}
+
+	if best == nil {
+		return "", nil, false
+	}
+
+	var params []string
+	for j, segment := range best.segments {
+		if segment == "{}" {
+			param, err := url.PathUnescape(segments[j])
+			if err != nil {
+				param = segments[j]
+			}
+
+			params = append(params, param)
+		}
+	}
+
+	return best.methodID, params, true
+}
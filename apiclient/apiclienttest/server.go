@@ -0,0 +1,460 @@
+// Package apiclienttest provides a fake Riot API server for testing code that uses apiclient.Client,
+// including how it behaves under rate limits, server errors and latency.
+//
+//	server := apiclienttest.NewServer()
+//	defer server.Close()
+//
+//	server.SetFixture(ratelimiter.GetSummonerByPuuid, apiclient.Summoner{Name: "Faker"})
+//	server.Inject(apiclienttest.Fault{MethodID: ratelimiter.GetSummonerByPuuid, Count: 1, StatusCode: 429, RetryAfter: time.Second})
+//
+//	client := server.NewClient()
+//	summoner, err := client.GetSummonerByPuuid(region.KR, puuid)
+package apiclienttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+)
+
+// Request is a request received by the Server.
+type Request struct {
+	// Region is the region or continent the request was sent to, e.g. "NA1" or "AMERICAS".
+	Region   string
+	MethodID ratelimiter.MethodID
+
+	// Params are the path parameters of the request, e.g. the PUUID of GetSummonerByPuuid.
+	Params []string
+	Query  url.Values
+	APIKey string
+	Header http.Header
+	Time   time.Time
+}
+
+// Response is the response of a Handler.
+type Response struct {
+	// StatusCode defaults to 200.
+	StatusCode int
+	Header     http.Header
+
+	// Body is sent as it is if it is a []byte or a string, and encoded as JSON otherwise.
+	Body interface{}
+}
+
+// Handler returns the response to a request for a method.
+type Handler func(req Request) Response
+
+// Fault makes the Server fail or delay requests.
+type Fault struct {
+	// MethodID and Region restrict the fault to the requests for a method or to a region or
+	// continent, if set.
+	MethodID ratelimiter.MethodID
+	Region   string
+
+	// Count is the number of requests the fault applies to. If it is 0, it applies to every
+	// request until ClearFaults is called.
+	Count int
+
+	// StatusCode is the status code of the responses, if set. Otherwise, the requests are
+	// only delayed and handled as usual.
+	StatusCode int
+
+	// RetryAfter and LimitType are sent as the Retry-After and X-Rate-Limit-Type headers, if set.
+	// LimitType defaults to "service" for 429 responses.
+	RetryAfter time.Duration
+	LimitType  string
+
+	// Latency is how long to wait before responding.
+	Latency time.Duration
+}
+
+// Default rate limits of the Server, which are high enough for most tests not to be throttled.
+var (
+	DefaultAppRateLimit    = []ratelimiter.Window{{Limit: 500, Duration: 10 * time.Second}, {Limit: 30000, Duration: 10 * time.Minute}}
+	DefaultMethodRateLimit = []ratelimiter.Window{{Limit: 2000, Duration: 10 * time.Second}}
+)
+
+// Server is a fake Riot API server. It serves every method of apiclient.Client, sends
+// X-App-Rate-Limit and X-Method-Rate-Limit headers with the counts of its requests, and
+// responds with 429 when a limit is exceeded, like the Riot API. Requests for methods
+// without a handler get a 404 response. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mutex        sync.Mutex
+	handlers     map[ratelimiter.MethodID]Handler
+	apiKey       string
+	appLimit     []ratelimiter.Window
+	methodLimits map[ratelimiter.MethodID][]ratelimiter.Window
+	counters     map[string]*counter
+	faults       []*Fault
+	requests     []Request
+}
+
+// NewServer starts a Server. The caller must call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		handlers:     make(map[ratelimiter.MethodID]Handler),
+		appLimit:     DefaultAppRateLimit,
+		methodLimits: make(map[ratelimiter.MethodID][]ratelimiter.Window),
+		counters:     make(map[string]*counter),
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// HostResolver sends the requests for each region and continent to the server.
+func (s *Server) HostResolver() apiclient.HostResolver {
+	return func(regionOrContinent apiclient.HostProvider) string {
+		return s.URL + "/" + strings.ToLower(regionOrContinent.String())
+	}
+}
+
+// NewClient returns a Client whose requests are sent to the server, configured with the given options.
+func (s *Server) NewClient(opts ...apiclient.Option) apiclient.Client {
+	opts = append([]apiclient.Option{apiclient.WithHostResolver(s.HostResolver())}, opts...)
+	return apiclient.New("RGAPI-apiclienttest", opts...)
+}
+
+// Handle sets the handler of a method's requests.
+func (s *Server) Handle(methodID ratelimiter.MethodID, handler Handler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.handlers[methodID] = handler
+}
+
+// SetFixture makes every request for a method succeed with body as its response.
+func (s *Server) SetFixture(methodID ratelimiter.MethodID, body interface{}) {
+	s.Handle(methodID, func(Request) Response {
+		return Response{Body: body}
+	})
+}
+
+// SetAPIKey makes the server respond with 403 to requests that are not sent with apiKey,
+// as the Riot API does for invalid keys. By default, every key is accepted.
+func (s *Server) SetAPIKey(apiKey string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.apiKey = apiKey
+}
+
+// SetAppRateLimit sets the application rate limit of each region and continent.
+// If no windows are given, requests are not limited and no X-App-Rate-Limit header is sent.
+func (s *Server) SetAppRateLimit(windows ...ratelimiter.Window) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.appLimit = windows
+	s.counters = make(map[string]*counter)
+}
+
+// SetMethodRateLimit sets the rate limit of a method in each region and continent.
+// If no windows are given, the method's requests are not limited and no X-Method-Rate-Limit
+// header is sent.
+func (s *Server) SetMethodRateLimit(methodID ratelimiter.MethodID, windows ...ratelimiter.Window) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.methodLimits[methodID] = windows
+	s.counters = make(map[string]*counter)
+}
+
+// Inject adds a fault. Faults are applied in the order they were added, and the first one
+// that applies to a request is used.
+func (s *Server) Inject(fault Fault) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes every fault.
+func (s *Server) ClearFaults() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults = nil
+}
+
+// Requests returns the requests received by the server, in the order they were received.
+func (s *Server) Requests() []Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// Count returns the number of requests received for a method, or for every method if methodID is empty.
+func (s *Server) Count(methodID ratelimiter.MethodID) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var count int
+	for _, req := range s.requests {
+		if methodID == "" || req.MethodID == methodID {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	// The first segment of the path is the region, added by HostResolver
+	region, path, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+
+	methodID, params, ok := match("/" + path)
+	if !ok {
+		writeStatus(w, http.StatusNotFound, "Resource not found")
+		return
+	}
+
+	req := Request{
+		Region:   strings.ToUpper(region),
+		MethodID: methodID,
+		Params:   params,
+		Query:    r.URL.Query(),
+		APIKey:   r.Header.Get("X-Riot-Token"),
+		Header:   r.Header.Clone(),
+		Time:     time.Now(),
+	}
+
+	s.mutex.Lock()
+	s.requests = append(s.requests, req)
+	fault := s.fault(req)
+	s.mutex.Unlock()
+
+	if fault.Latency > 0 {
+		select {
+		case <-time.After(fault.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if fault.StatusCode != 0 {
+		if fault.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int((fault.RetryAfter+time.Second-1)/time.Second)))
+		}
+
+		if fault.LimitType != "" {
+			w.Header().Set("X-Rate-Limit-Type", fault.LimitType)
+		} else if fault.StatusCode == http.StatusTooManyRequests {
+			w.Header().Set("X-Rate-Limit-Type", "service")
+		}
+
+		writeStatus(w, fault.StatusCode, http.StatusText(fault.StatusCode))
+		return
+	}
+
+	s.mutex.Lock()
+	apiKey := s.apiKey
+	handler := s.handlers[methodID]
+	s.mutex.Unlock()
+
+	if req.APIKey == "" {
+		writeStatus(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if apiKey != "" && req.APIKey != apiKey {
+		writeStatus(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	if !s.takeRateLimits(w, req) {
+		return
+	}
+
+	if handler == nil {
+		writeStatus(w, http.StatusNotFound, "Data not found")
+		return
+	}
+
+	resp := handler(req)
+	if resp.StatusCode == 0 {
+		resp.StatusCode = http.StatusOK
+	}
+
+	var body []byte
+	switch b := resp.Body.(type) {
+	case []byte:
+		body = b
+	case string:
+		body = []byte(b)
+	default:
+		var err error
+		if body, err = json.Marshal(b); err != nil {
+			writeStatus(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	}
+
+	w.WriteHeader(resp.StatusCode)
+	w.Write(body)
+}
+
+// fault returns the first fault that applies to the request, using up one of its requests.
+// It returns the zero Fault if none applies. The caller must hold s.mutex.
+func (s *Server) fault(req Request) Fault {
+	for i, fault := range s.faults {
+		if fault.MethodID != "" && fault.MethodID != req.MethodID {
+			continue
+		}
+
+		if fault.Region != "" && !strings.EqualFold(fault.Region, req.Region) {
+			continue
+		}
+
+		if fault.Count > 0 {
+			fault.Count--
+			if fault.Count == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+
+		return *fault
+	}
+
+	return Fault{}
+}
+
+// takeRateLimits counts the request in its region's and method's rate limits and sets the
+// rate limit headers. If a limit is exceeded, it responds with 429 and returns false.
+func (s *Server) takeRateLimits(w http.ResponseWriter, req Request) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	methodLimit, ok := s.methodLimits[req.MethodID]
+	if !ok {
+		methodLimit = DefaultMethodRateLimit
+	}
+
+	app := s.counter(req.Region, s.appLimit)
+	method := s.counter(req.Region+":"+req.MethodID.String(), methodLimit)
+
+	now := time.Now()
+	limitType := "application"
+	retryAfter := app.exceeded(now)
+	if wait := method.exceeded(now); wait > retryAfter {
+		limitType = "method"
+		retryAfter = wait
+	}
+
+	if retryAfter == 0 {
+		app.take()
+		method.take()
+	}
+
+	app.setHeaders(w.Header(), "X-App-Rate-Limit")
+	method.setHeaders(w.Header(), "X-Method-Rate-Limit")
+
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+		w.Header().Set("X-Rate-Limit-Type", limitType)
+		writeStatus(w, http.StatusTooManyRequests, "Rate limit exceeded")
+		return false
+	}
+
+	return true
+}
+
+// counter returns the counter of a rate limit, creating it if needed. The caller must hold s.mutex.
+func (s *Server) counter(key string, windows []ratelimiter.Window) *counter {
+	c, ok := s.counters[key]
+	if !ok {
+		c = &counter{
+			windows: windows,
+			starts:  make([]time.Time, len(windows)),
+			counts:  make([]int, len(windows)),
+		}
+
+		s.counters[key] = c
+	}
+
+	return c
+}
+
+// counter counts the requests of a rate limit in fixed windows, which start with their first request.
+type counter struct {
+	windows []ratelimiter.Window
+	starts  []time.Time
+	counts  []int
+}
+
+// exceeded resets the windows that have ended and returns how long until the request fits in
+// every window, or 0 if it already does.
+func (c *counter) exceeded(now time.Time) time.Duration {
+	var wait time.Duration
+	for i, window := range c.windows {
+		if c.starts[i].IsZero() || !now.Before(c.starts[i].Add(window.Duration)) {
+			c.starts[i] = now
+			c.counts[i] = 0
+		}
+
+		if c.counts[i] >= window.Limit {
+			if until := c.starts[i].Add(window.Duration).Sub(now); until > wait {
+				wait = until
+			}
+		}
+	}
+
+	return wait
+}
+
+func (c *counter) take() {
+	for i := range c.counts {
+		c.counts[i]++
+	}
+}
+
+func (c *counter) setHeaders(header http.Header, name string) {
+	if len(c.windows) == 0 {
+		return
+	}
+
+	limits := make([]string, len(c.windows))
+	counts := make([]string, len(c.windows))
+	for i, window := range c.windows {
+		seconds := int(window.Duration / time.Second)
+		limits[i] = fmt.Sprintf("%d:%d", window.Limit, seconds)
+		counts[i] = fmt.Sprintf("%d:%d", c.counts[i], seconds)
+	}
+
+	header.Set(name, strings.Join(limits, ","))
+	header.Set(name+"-Count", strings.Join(counts, ","))
+}
+
+// writeStatus writes an error response in the format of the Riot API.
+func writeStatus(w http.ResponseWriter, statusCode int, message string) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"message":     message,
+			"status_code": statusCode,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
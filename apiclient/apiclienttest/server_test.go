@@ -0,0 +1,285 @@
+package apiclienttest_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/apiclienttest"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+	"github.com/Kinveil/Riot-API-Golang/constants/continent"
+	"github.com/Kinveil/Riot-API-Golang/constants/league/rank"
+	"github.com/Kinveil/Riot-API-Golang/constants/league/tier"
+	"github.com/Kinveil/Riot-API-Golang/constants/region"
+)
+
+// TestRoutes calls every method of the client, and checks that the server routes each
+// request to the method that sent it, with its path parameters.
+func TestRoutes(t *testing.T) {
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	client := server.NewClient()
+	const queue = "RANKED_SOLO_5x5"
+
+	tests := []struct {
+		methodID ratelimiter.MethodID
+		region   string
+		params   []string
+		call     func() error
+	}{
+		{ratelimiter.GetAccountByPuuid, "AMERICAS", []string{"puuid"}, func() error {
+			_, err := client.GetAccountByPuuid(continent.AMERICAS, "puuid")
+			return err
+		}},
+		{ratelimiter.GetAccountByRiotID, "AMERICAS", []string{"Hide on bush", "KR 1"}, func() error {
+			_, err := client.GetAccountByRiotID(continent.AMERICAS, "Hide on bush", "KR 1")
+			return err
+		}},
+		{ratelimiter.GetChampionMasteriesBySummonerID, "NA1", []string{"summoner"}, func() error {
+			_, err := client.GetChampionMasteriesBySummonerID(region.NA1, "summoner")
+			return err
+		}},
+		{ratelimiter.GetChampionMasteryBySummonerIDAndChampionID, "NA1", []string{"summoner", "103"}, func() error {
+			_, err := client.GetChampionMasteryBySummonerIDAndChampionID(region.NA1, "summoner", 103)
+			return err
+		}},
+		{ratelimiter.GetChampionMasteriesTopBySummonerID, "NA1", []string{"summoner"}, func() error {
+			_, err := client.GetChampionMasteriesTopBySummonerID(region.NA1, "summoner")
+			return err
+		}},
+		{ratelimiter.GetChampionMasteryScoreTotalBySummonerID, "NA1", []string{"summoner"}, func() error {
+			_, err := client.GetChampionMasteryScoreTotalBySummonerID(region.NA1, "summoner")
+			return err
+		}},
+		{ratelimiter.GetChampionRotations, "NA1", nil, func() error {
+			_, err := client.GetChampionRotations(region.NA1)
+			return err
+		}},
+		{ratelimiter.GetClashPlayersByPuuid, "NA1", []string{"puuid"}, func() error {
+			_, err := client.GetClashPlayersByPuuid(region.NA1, "puuid")
+			return err
+		}},
+		{ratelimiter.GetClashPlayersBySummonerID, "NA1", []string{"summoner"}, func() error {
+			_, err := client.GetClashPlayersBySummonerID(region.NA1, "summoner")
+			return err
+		}},
+		{ratelimiter.GetClashTeamByID, "NA1", []string{"team"}, func() error {
+			_, err := client.GetClashTeamByID(region.NA1, "team")
+			return err
+		}},
+		{ratelimiter.GetClashTournaments, "NA1", nil, func() error {
+			_, err := client.GetClashTournaments(region.NA1)
+			return err
+		}},
+		{ratelimiter.GetClashTournamentByTeamID, "NA1", []string{"team"}, func() error {
+			_, err := client.GetClashTournamentByTeamID(region.NA1, "team")
+			return err
+		}},
+		{ratelimiter.GetClashTournamentByID, "NA1", []string{"tournament"}, func() error {
+			_, err := client.GetClashTournamentByID(region.NA1, "tournament")
+			return err
+		}},
+		{ratelimiter.GetLeagueExpEntries, "NA1", []string{queue, "DIAMOND", "I"}, func() error {
+			_, err := client.GetLeagueExpEntries(region.NA1, queue, tier.Diamond, rank.I, 1)
+			return err
+		}},
+		{ratelimiter.GetLeagueEntriesChallenger, "NA1", []string{queue}, func() error {
+			_, err := client.GetLeagueEntriesChallenger(region.NA1, queue)
+			return err
+		}},
+		{ratelimiter.GetLeagueEntriesGrandmaster, "NA1", []string{queue}, func() error {
+			_, err := client.GetLeagueEntriesGrandmaster(region.NA1, queue)
+			return err
+		}},
+		{ratelimiter.GetLeagueEntriesMaster, "NA1", []string{queue}, func() error {
+			_, err := client.GetLeagueEntriesMaster(region.NA1, queue)
+			return err
+		}},
+		{ratelimiter.GetLeagueEntries, "NA1", []string{queue, "DIAMOND", "I"}, func() error {
+			_, err := client.GetLeagueEntries(region.NA1, queue, tier.Diamond, rank.I, 1)
+			return err
+		}},
+		{ratelimiter.GetLeagueEntriesByID, "NA1", []string{"league"}, func() error {
+			_, err := client.GetLeagueEntriesByID(region.NA1, "league")
+			return err
+		}},
+		{ratelimiter.GetLeagueEntriesBySummonerID, "NA1", []string{"summoner"}, func() error {
+			_, err := client.GetLeagueEntriesBySummonerID(region.NA1, "summoner")
+			return err
+		}},
+		{ratelimiter.GetChallengesConfig, "NA1", nil, func() error {
+			_, err := client.GetChallengesConfig(region.NA1)
+			return err
+		}},
+		{ratelimiter.GetChallengesPercentiles, "NA1", nil, func() error {
+			_, err := client.GetChallengesPercentiles(region.NA1)
+			return err
+		}},
+		{ratelimiter.GetChallengesConfigByID, "NA1", []string{"101"}, func() error {
+			_, err := client.GetChallengesConfigByID(region.NA1, "101")
+			return err
+		}},
+		{ratelimiter.GetChallengesLeaderboardsByLevel, "NA1", []string{"101", "MASTER"}, func() error {
+			_, err := client.GetChallengesLeaderboardsByLevel(region.NA1, "101", "MASTER")
+			return err
+		}},
+		{ratelimiter.GetChallengesPercentilesByID, "NA1", []string{"101"}, func() error {
+			_, err := client.GetChallengesPercentilesByID(region.NA1, "101")
+			return err
+		}},
+		{ratelimiter.GetChallengesPlayerDataByPuuid, "NA1", []string{"puuid"}, func() error {
+			_, err := client.GetChallengesPlayerDataByPuuid(region.NA1, "puuid")
+			return err
+		}},
+		{ratelimiter.GetStatusPlatformData, "NA1", nil, func() error {
+			_, err := client.GetStatusPlatformData(region.NA1)
+			return err
+		}},
+		{ratelimiter.GetMatchlist, "AMERICAS", []string{"puuid"}, func() error {
+			_, err := client.GetMatchlist(continent.AMERICAS, "puuid", nil)
+			return err
+		}},
+		{ratelimiter.GetMatch, "AMERICAS", []string{"NA1_1"}, func() error {
+			_, err := client.GetMatch(continent.AMERICAS, "NA1_1")
+			return err
+		}},
+		{ratelimiter.GetMatchTimeline, "AMERICAS", []string{"NA1_1"}, func() error {
+			_, err := client.GetMatchTimeline(continent.AMERICAS, "NA1_1")
+			return err
+		}},
+		{ratelimiter.GetSpectatorActiveGameBySummonerID, "NA1", []string{"summoner"}, func() error {
+			_, err := client.GetSpectatorActiveGameBySummonerID(region.NA1, "summoner")
+			return err
+		}},
+		{ratelimiter.GetSpectatorFeaturedGames, "NA1", nil, func() error {
+			_, err := client.GetSpectatorFeaturedGames(region.NA1)
+			return err
+		}},
+		// GetSummonerByRsoPuuid is sent to the same path as GetSummonerByAccountID
+		{ratelimiter.GetSummonerByAccountID, "NA1", []string{"rso"}, func() error {
+			_, err := client.GetSummonerByRsoPuuid(region.NA1, "rso")
+			return err
+		}},
+		{ratelimiter.GetSummonerByAccountID, "NA1", []string{"account"}, func() error {
+			_, err := client.GetSummonerByAccountID(region.NA1, "account")
+			return err
+		}},
+		{ratelimiter.GetSummonerByName, "NA1", []string{"Faker"}, func() error {
+			_, err := client.GetSummonerByName(region.NA1, "Faker")
+			return err
+		}},
+		{ratelimiter.GetSummonerByPuuid, "NA1", []string{"puuid"}, func() error {
+			_, err := client.GetSummonerByPuuid(region.NA1, "puuid")
+			return err
+		}},
+		{ratelimiter.GetSummonerBySummonerID, "NA1", []string{"summoner"}, func() error {
+			_, err := client.GetSummonerBySummonerID(region.NA1, "summoner")
+			return err
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.methodID.String(), func(t *testing.T) {
+			// A JSON null decodes into the result of every method. Responding with 200 also
+			// lets the client learn the rate limits of the server.
+			server.SetFixture(test.methodID, "null")
+			before := len(server.Requests())
+
+			if err := test.call(); err != nil {
+				t.Fatalf("call = %v", err)
+			}
+
+			requests := server.Requests()
+			if len(requests) != before+1 {
+				t.Fatalf("server received %d requests, want 1", len(requests)-before)
+			}
+
+			req := requests[before]
+			if req.MethodID != test.methodID || req.Region != test.region || !reflect.DeepEqual(req.Params, test.params) {
+				t.Errorf("request routed to %s in %s with %q, want %s in %s with %q",
+					req.MethodID, req.Region, req.Params, test.methodID, test.region, test.params)
+			}
+		})
+	}
+}
+
+func TestRateLimitedThenRetried(t *testing.T) {
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(ratelimiter.GetSummonerByPuuid, apiclient.Summoner{Name: "Faker"})
+	server.Inject(apiclienttest.Fault{
+		MethodID:   ratelimiter.GetSummonerByPuuid,
+		Count:      1,
+		StatusCode: http.StatusTooManyRequests,
+		RetryAfter: time.Second,
+		LimitType:  "method",
+	})
+
+	var info apiclient.ResponseInfo
+	start := time.Now()
+
+	summoner, err := server.NewClient().WithResponseInfo(&info).GetSummonerByPuuid(region.KR, "puuid")
+	if err != nil {
+		t.Fatalf("GetSummonerByPuuid() = %v", err)
+	}
+
+	if summoner.Name != "Faker" || info.Retries != 1 || server.Count(ratelimiter.GetSummonerByPuuid) != 2 {
+		t.Errorf("got %q after %d retries and %d requests, want Faker after 1 retry and 2 requests",
+			summoner.Name, info.Retries, server.Count(ratelimiter.GetSummonerByPuuid))
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, before Retry-After", elapsed)
+	}
+}
+
+func TestServerErrorFault(t *testing.T) {
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(ratelimiter.GetMatch, apiclient.Match{})
+	server.Inject(apiclienttest.Fault{
+		MethodID:   ratelimiter.GetMatch,
+		Region:     "EUROPE",
+		StatusCode: http.StatusServiceUnavailable,
+	})
+
+	client := server.NewClient()
+
+	// Server errors from the Match API are not retried by default
+	_, err := client.GetMatch(continent.EUROPE, "EUW1_1")
+	if !errors.Is(err, apiclient.ErrServiceUnavailable) {
+		t.Fatalf("GetMatch() = %v, want %v", err, apiclient.ErrServiceUnavailable)
+	}
+
+	if count := server.Count(ratelimiter.GetMatch); count != 1 {
+		t.Errorf("server received %d requests, want 1", count)
+	}
+
+	// The fault only applies to its region
+	if _, err := client.GetMatch(continent.AMERICAS, "NA1_1"); err != nil {
+		t.Errorf("GetMatch() in another region = %v", err)
+	}
+
+	server.ClearFaults()
+
+	if _, err := client.GetMatch(continent.EUROPE, "EUW1_1"); err != nil {
+		t.Errorf("GetMatch() after ClearFaults() = %v", err)
+	}
+}
+
+func TestMethodWithoutHandler(t *testing.T) {
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	var requestErr *apiclient.RequestError
+	_, err := server.NewClient().GetSummonerByPuuid(region.KR, "puuid")
+	if !errors.As(err, &requestErr) || requestErr.StatusCode != http.StatusNotFound || requestErr.Message != "Data not found" {
+		t.Fatalf("GetSummonerByPuuid() = %v, want a 404 for a method without a handler", err)
+	}
+}
@@ -0,0 +1,122 @@
+package apiclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// maxPooledBodySize is the capacity above which a body's buffer is not reused, so that a rare
+// huge response does not keep its memory alive in the pool.
+const maxPooledBodySize = 16 << 20
+
+// bodyPool holds the buffers that response bodies are read into, so that large responses
+// such as match timelines do not allocate a new body for every call.
+var bodyPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// readBody reads a body into a buffer from bodyPool. The buffer is sized from the response's
+// Content-Length, so that it is read without growing the buffer.
+func readBody(body io.Reader, contentLength int64) (*bytes.Buffer, error) {
+	buffer := bodyPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+
+	if contentLength > 0 && contentLength <= maxPooledBodySize {
+		// ReadFrom grows the buffer unless MinRead bytes are free, even once the body is read
+		buffer.Grow(int(contentLength) + bytes.MinRead)
+	}
+
+	if _, err := buffer.ReadFrom(body); err != nil {
+		putBuffer(buffer)
+		return nil, err
+	}
+
+	return buffer, nil
+}
+
+// readStream reads up to limit bytes of a streamed body into Body, so that the response
+// can be handled like one whose body was read by the client.
+func (r *Response) readStream(limit int64) error {
+	buffer, err := readBody(io.LimitReader(r.stream, limit), 0)
+	if err != nil {
+		return err
+	}
+
+	r.buffer = buffer
+	r.Body = buffer.Bytes()
+	return nil
+}
+
+// release closes the response's stream and returns the buffer holding its body to bodyPool,
+// if it has them. The body must not be used after.
+func (r *Response) release() {
+	if r.stream != nil {
+		r.stream.Close()
+		r.stream = nil
+	}
+
+	if r.buffer == nil {
+		return
+	}
+
+	putBuffer(r.buffer)
+	r.buffer = nil
+	r.Body = nil
+}
+
+func putBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() <= maxPooledBodySize {
+		bodyPool.Put(buffer)
+	}
+}
+
+// streamDecoder is implemented by results that are decoded token by token from a streamed
+// body, so that a large response such as a match timeline is never held in memory at once.
+type streamDecoder interface {
+	decodeStream(dec *json.Decoder) error
+}
+
+// decodeStream decodes a streamed body into dest, then reads the rest of the body, so that
+// its connection can be reused.
+func decodeStream(body io.Reader, dest interface{}) error {
+	dec := json.NewDecoder(body)
+
+	var err error
+	if streamer, ok := dest.(streamDecoder); ok {
+		err = streamer.decodeStream(dec)
+	} else {
+		err = dec.Decode(dest)
+	}
+
+	// The decoder reports a body that ends before its value does as io.EOF between tokens
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(io.Discard, body)
+	return err
+}
+
+// readErrorRecorder records the error of a failed read, so that a body that could not be
+// read is told apart from one that could not be decoded.
+type readErrorRecorder struct {
+	io.Reader
+	err error
+}
+
+func (r *readErrorRecorder) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+
+	return n, err
+}
@@ -0,0 +1,169 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const streamedTimeline = `{
+	"metadata": {"dataVersion": "2", "matchId": "NA1_1", "participants": ["a", "b"]},
+	"unknown": {"frames": [1, 2]},
+	"info": {
+		"frameInterval": 60000,
+		"frames": [
+			{
+				"timestamp": 0,
+				"participantFrames": {"2": {"participantId": 2, "level": 1}, "1": {"participantId": 1, "level": 2}},
+				"events": [
+					{"type": "CHAMPION_KILL", "killerId": 1, "assistingParticipantIds": [2], "timestamp": 10},
+					{"type": "NEW_EVENT", "timestamp": 11}
+				]
+			},
+			{"timestamp": 60000, "participantFrames": null, "events": []}
+		],
+		"gameId": 1,
+		"participants": [{"participantId": 1, "puuid": "a"}],
+		"endOfGameResult": "GameComplete"
+	}
+}`
+
+func TestDecodeStreamTimeline(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"timeline", streamedTimeline},
+		{"null", "null"},
+		{"null frames", `{"info": {"frames": null, "gameId": 1}}`},
+		{"empty frames", `{"info": {"frames": []}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var streamed, unmarshaled MatchTimeline
+			if err := decodeStream(strings.NewReader(test.body), &streamed); err != nil {
+				t.Fatalf("decodeStream() = %v", err)
+			}
+
+			if err := json.Unmarshal([]byte(test.body), &unmarshaled); err != nil {
+				t.Fatalf("json.Unmarshal() = %v", err)
+			}
+
+			if !reflect.DeepEqual(streamed, unmarshaled) {
+				t.Errorf("decodeStream() = %+v, want %+v", streamed, unmarshaled)
+			}
+		})
+	}
+}
+
+// fill sets every field reachable from v to a value other than its zero value. Frames are left
+// empty, since they are decoded by MatchTimelineFrame.UnmarshalJSON rather than by their tags.
+func fill(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Int:
+		v.SetInt(1)
+	case reflect.String:
+		v.SetString("a")
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(v.Index(0))
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(MatchTimelineFrame{}) {
+			return
+		}
+
+		for i := 0; i < v.NumField(); i++ {
+			fill(v.Field(i))
+		}
+	}
+}
+
+// TestDecodeStreamMatchesTags checks that each field decoded by a decodeStream method is decoded
+// from the key in its JSON tag, matched as json.Unmarshal matches it, so that they cannot drift apart.
+func TestDecodeStreamMatchesTags(t *testing.T) {
+	for _, typ := range []reflect.Type{reflect.TypeOf(MatchTimeline{}), reflect.TypeOf(MatchTimelineInfo{})} {
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+			value := reflect.New(field.Type).Elem()
+			fill(value)
+
+			data, err := json.Marshal(value.Interface())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, key := range []string{name, strings.ToUpper(name), strings.ToLower(name)} {
+				body := fmt.Sprintf(`{%q: %s}`, key, data)
+				if typ == reflect.TypeOf(MatchTimelineInfo{}) {
+					body = fmt.Sprintf(`{"info": %s}`, body)
+				}
+
+				var streamed, unmarshaled MatchTimeline
+				if err := decodeStream(strings.NewReader(body), &streamed); err != nil {
+					t.Fatalf("decodeStream(%s) = %v", body, err)
+				}
+
+				if err := json.Unmarshal([]byte(body), &unmarshaled); err != nil {
+					t.Fatalf("json.Unmarshal(%s) = %v", body, err)
+				}
+
+				if reflect.DeepEqual(unmarshaled, MatchTimeline{}) || !reflect.DeepEqual(streamed, unmarshaled) {
+					t.Errorf("decodeStream(%s) = %+v, json.Unmarshal = %+v", body, streamed, unmarshaled)
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeStreamInvalid(t *testing.T) {
+	for _, body := range []string{
+		`[]`,
+		`{"info": {"frames": {}}}`,
+		`{"info": {"frames": [{"timestamp": 0}`,
+		`{"metadata": {"matchId": 1}}`,
+	} {
+		var timeline MatchTimeline
+		if err := decodeStream(strings.NewReader(body), &timeline); err == nil {
+			t.Errorf("decodeStream(%s) = nil, want an error", body)
+		}
+	}
+}
+
+// failingReader returns its data, then err.
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestDecodeStreamReadError(t *testing.T) {
+	readErr := errors.New("connection reset")
+	body := &readErrorRecorder{Reader: &failingReader{data: streamedTimeline[:200], err: readErr}}
+
+	var timeline MatchTimeline
+	if err := decodeStream(body, &timeline); err == nil || body.err != readErr {
+		t.Errorf("decodeStream() = %v with read error %v, want %v", err, body.err, readErr)
+	}
+
+	// A body that is read to its end has no read error, even if it cannot be decoded
+	body = &readErrorRecorder{Reader: strings.NewReader(`{"info": `)}
+	if err := decodeStream(body, &timeline); err != io.ErrUnexpectedEOF || body.err != nil {
+		t.Errorf("decodeStream() = %v with read error %v, want %v", err, body.err, io.ErrUnexpectedEOF)
+	}
+}
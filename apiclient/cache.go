@@ -0,0 +1,131 @@
+package apiclient
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/cache"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+)
+
+// CacheOptions configures the response cache set with WithCache.
+type CacheOptions struct {
+	// TTLs is how long the responses of each method stay fresh. Only the responses of the
+	// methods in TTLs are cached. Defaults to DefaultCacheTTLs.
+	TTLs map[ratelimiter.MethodID]time.Duration
+
+	// MaxStale is how long responses are kept after they stop being fresh, so that calls made
+	// with WithStaleWhileRevalidate can return them while they are refreshed in the background.
+	MaxStale time.Duration
+}
+
+// DefaultCacheTTLs are the TTLs of the responses that do not change once they exist.
+var DefaultCacheTTLs = map[ratelimiter.MethodID]time.Duration{
+	ratelimiter.GetAccountByPuuid: 6 * time.Hour,
+	ratelimiter.GetMatch:          24 * time.Hour,
+	ratelimiter.GetMatchTimeline:  24 * time.Hour,
+}
+
+// responseCache looks up the responses of calls in a cache before sending them.
+type responseCache struct {
+	cache    cache.Cache
+	ttls     map[ratelimiter.MethodID]time.Duration
+	maxStale time.Duration
+
+	// revalidating holds the keys of the stale responses being refreshed in the background.
+	mutex        sync.Mutex
+	revalidating map[string]bool
+}
+
+func newResponseCache(c cache.Cache, options CacheOptions) *responseCache {
+	if options.TTLs == nil {
+		options.TTLs = DefaultCacheTTLs
+	}
+
+	return &responseCache{
+		cache:        c,
+		ttls:         options.TTLs,
+		maxStale:     options.MaxStale,
+		revalidating: make(map[string]bool),
+	}
+}
+
+// dispatchCached returns the cached response of the call if it is fresh, or if it is stale and the
+// client allows stale responses, in which case it is refreshed in the background. Otherwise, the call
+// is sent with send and its response is cached. Cache errors are ignored, so that calls still succeed
+// while the cache is unavailable.
+func (c *client) dispatchCached(regionOrContinent HostProvider, URL string, methodID ratelimiter.MethodID, ttl time.Duration, dest interface{}, send func(c *client, dest interface{}) (*Response, error)) (*Response, error) {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	key := c.ratelimiter.KeyHash(c.apiKeyName) + ":" + URL
+
+	if !c.cacheBypass {
+		// The cached value is loaded into a new value, so that dest is only filled if it is returned
+		cached := reflect.New(reflect.TypeOf(dest).Elem())
+		storedAt, ok, err := c.cache.cache.Get(ctx, key, cached.Interface())
+		if err == nil && ok {
+			age := time.Since(storedAt)
+			if age < ttl || (c.staleWhileRevalidate && age < ttl+c.cache.maxStale) {
+				if age >= ttl {
+					c.revalidate(regionOrContinent, URL, methodID, key, ttl, cached.Type().Elem(), send)
+				}
+
+				reflect.ValueOf(dest).Elem().Set(cached.Elem())
+
+				if c.responseInfo != nil {
+					*c.responseInfo = ResponseInfo{
+						Region:   strings.ToUpper(regionOrContinent.String()),
+						MethodID: methodID,
+						URL:      URL,
+						Cached:   true,
+					}
+				}
+
+				return nil, nil
+			}
+		}
+	}
+
+	response, err := send(c, dest)
+	if err == nil {
+		c.cache.cache.Set(ctx, key, dest, ttl+c.cache.maxStale)
+	}
+
+	return response, err
+}
+
+// revalidate refreshes a stale cached response in the background, unless it is already being refreshed.
+func (c *client) revalidate(regionOrContinent HostProvider, URL string, methodID ratelimiter.MethodID, key string, ttl time.Duration, destType reflect.Type, send func(c *client, dest interface{}) (*Response, error)) {
+	c.cache.mutex.Lock()
+	if c.cache.revalidating[key] {
+		c.cache.mutex.Unlock()
+		return
+	}
+
+	c.cache.revalidating[key] = true
+	c.cache.mutex.Unlock()
+
+	// The refresh must outlive the call, so it does not use the call's context or fill its ResponseInfo
+	cc := *c
+	cc.ctx = nil
+	cc.responseInfo = nil
+
+	go func() {
+		defer func() {
+			c.cache.mutex.Lock()
+			delete(c.cache.revalidating, key)
+			c.cache.mutex.Unlock()
+		}()
+
+		dest := reflect.New(destType).Interface()
+		if _, err := send(&cc, dest); err == nil {
+			cc.cache.cache.Set(context.Background(), key, dest, ttl+cc.cache.maxStale)
+		}
+	}()
+}
@@ -0,0 +1,18 @@
+// Package cache provides caches for the decoded responses of the Riot API client.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores the decoded responses of calls. It must be safe for concurrent use.
+type Cache interface {
+	// Get loads the value stored under key into dest, a pointer of the same type as the value
+	// that was stored, and returns the time it was stored. ok is false if no value is stored
+	// under key, or if it has expired. dest must be left as it was unless ok is true.
+	Get(ctx context.Context, key string, dest interface{}) (storedAt time.Time, ok bool, err error)
+
+	// Set stores value, a pointer to a decoded response, under key until ttl has passed.
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+}
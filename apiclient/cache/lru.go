@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// LRU is a Cache that keeps up to a fixed number of values in memory, evicting the least
+// recently used value when it is full.
+//
+// Values are stored as they are and shallow copied into dest, so the slices and maps in
+// them are shared between the callers that get them and must not be modified.
+type LRU struct {
+	mutex   sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type lruEntry struct {
+	key       string
+	value     reflect.Value
+	storedAt  time.Time
+	expiresAt time.Time
+}
+
+// NewLRU returns an LRU that holds up to size values.
+func NewLRU(size int) *LRU {
+	if size <= 0 {
+		size = 1
+	}
+
+	return &LRU{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (c *LRU) Get(ctx context.Context, key string, dest interface{}) (time.Time, bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	entry := element.Value.(*lruEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		c.remove(element)
+		return time.Time{}, false, nil
+	}
+
+	destValue := reflect.ValueOf(dest)
+	if destValue.Type() != entry.value.Type() {
+		return time.Time{}, false, nil
+	}
+
+	destValue.Elem().Set(entry.value.Elem())
+	c.order.MoveToFront(element)
+
+	return entry.storedAt, true, nil
+}
+
+func (c *LRU) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Ptr {
+		return nil
+	}
+
+	// Copy the value, so that the caller cannot modify the stored one
+	stored := reflect.New(v.Type().Elem())
+	stored.Elem().Set(v.Elem())
+
+	now := time.Now()
+	entry := &lruEntry{
+		key:       key,
+		value:     stored,
+		storedAt:  now,
+		expiresAt: now.Add(ttl),
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+
+	return nil
+}
+
+// Len returns the number of values in the cache, including expired values that have not been evicted yet.
+func (c *LRU) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.order.Len()
+}
+
+// remove removes an entry. The caller must hold c.mutex.
+func (c *LRU) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*lruEntry).key)
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type value struct {
+	Name  string
+	Level int
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+
+	c.Set(ctx, "a", &value{Name: "a"}, time.Hour)
+	c.Set(ctx, "b", &value{Name: "b"}, time.Hour)
+
+	// Getting a makes b the least recently used value
+	var got value
+	if _, ok, err := c.Get(ctx, "a", &got); !ok || err != nil || got.Name != "a" {
+		t.Fatalf("Get(a) = %+v, %v, %v, want a", got, ok, err)
+	}
+
+	c.Set(ctx, "c", &value{Name: "c"}, time.Hour)
+
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+
+	if _, ok, _ := c.Get(ctx, "b", &got); ok {
+		t.Error("Get(b) found the least recently used value, want it evicted")
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := c.Get(ctx, key, &got); !ok || got.Name != key {
+			t.Errorf("Get(%s) = %+v, %v, want %s", key, got, ok, key)
+		}
+	}
+
+	// Setting a stored key replaces its value without evicting another one
+	c.Set(ctx, "a", &value{Name: "a", Level: 2}, time.Hour)
+
+	if _, ok, _ := c.Get(ctx, "a", &got); !ok || got.Level != 2 {
+		t.Errorf("Get(a) after replacing it = %+v, %v, want level 2", got, ok)
+	}
+
+	if _, ok, _ := c.Get(ctx, "c", &got); !ok {
+		t.Error("Get(c) after replacing a = not found")
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+
+	before := time.Now()
+	c.Set(ctx, "a", &value{Name: "a"}, 50*time.Millisecond)
+
+	var got value
+	storedAt, ok, err := c.Get(ctx, "a", &got)
+	if !ok || err != nil || got.Name != "a" || storedAt.Before(before) || storedAt.After(time.Now()) {
+		t.Fatalf("Get(a) = %v, %v, %v, want a stored just now", storedAt, ok, err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	got = value{Name: "unchanged"}
+	if _, ok, err := c.Get(ctx, "a", &got); ok || err != nil || got.Name != "unchanged" {
+		t.Errorf("Get(a) after its TTL = %+v, %v, %v, want an untouched miss", got, ok, err)
+	}
+
+	if c.Len() != 0 {
+		t.Errorf("Len() after getting an expired value = %d, want 0", c.Len())
+	}
+}
+
+func TestLRUCopiesValues(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+
+	stored := &value{Name: "a"}
+	c.Set(ctx, "a", stored, time.Hour)
+	stored.Name = "modified"
+
+	var got value
+	if _, ok, _ := c.Get(ctx, "a", &got); !ok || got.Name != "a" {
+		t.Errorf("Get(a) after modifying the stored value = %+v, %v, want a", got, ok)
+	}
+
+	// A value is not loaded into a dest of another type
+	other := struct{ Name string }{Name: "unchanged"}
+	if _, ok, _ := c.Get(ctx, "a", &other); ok || other.Name != "unchanged" {
+		t.Errorf("Get(a) into another type = %+v, %v, want an untouched miss", other, ok)
+	}
+}
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/internal/redis"
+)
+
+// RedisOptions configures a Redis cache.
+type RedisOptions struct {
+	// Addr is the host:port address of the Redis server.
+	Addr string
+
+	// Password is used to authenticate with the server, if set.
+	Password string
+
+	// DB is the database to select, if set.
+	DB int
+
+	// Prefix is prepended to every key. Defaults to "riot-cache".
+	Prefix string
+
+	// PoolSize is the maximum number of idle connections. Defaults to 10.
+	PoolSize int
+}
+
+// Redis is a Cache that stores values in a Redis server, so that several processes can
+// share them. Values are encoded with their MarshalBinary method, such as the one of
+// apiclient.Summoner, or as JSON if they do not have one, and expire on the server.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedis(opts RedisOptions) *Redis {
+	if opts.Prefix == "" {
+		opts.Prefix = "riot-cache"
+	}
+
+	return &Redis{
+		client: redis.NewClient(redis.Options{
+			Addr:     opts.Addr,
+			Password: opts.Password,
+			DB:       opts.DB,
+			PoolSize: opts.PoolSize,
+		}),
+		prefix: opts.Prefix,
+	}
+}
+
+// Close closes the connections to the Redis server.
+func (c *Redis) Close() error {
+	return c.client.Close()
+}
+
+func (c *Redis) key(key string) string {
+	return c.prefix + ":" + key
+}
+
+func (c *Redis) Get(ctx context.Context, key string, dest interface{}) (time.Time, bool, error) {
+	reply, err := c.client.Do(ctx, "GET", c.key(key))
+	if err != nil || reply == nil {
+		return time.Time{}, false, err
+	}
+
+	data, ok := reply.(string)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("cache: unexpected reply %v from redis", reply)
+	}
+
+	millis, encoded, ok := strings.Cut(data, "\n")
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("cache: malformed value for %s", key)
+	}
+
+	storedAt, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("cache: malformed value for %s", key)
+	}
+
+	// Decode into a new value, so that dest is left as it was if the value cannot be decoded
+	value := reflect.New(reflect.TypeOf(dest).Elem())
+	if unmarshaler, ok := value.Interface().(encoding.BinaryUnmarshaler); ok {
+		err = unmarshaler.UnmarshalBinary([]byte(encoded))
+	} else {
+		err = json.Unmarshal([]byte(encoded), value.Interface())
+	}
+
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	reflect.ValueOf(dest).Elem().Set(value.Elem())
+
+	return time.UnixMilli(storedAt), true, nil
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if ttl.Milliseconds() <= 0 {
+		return nil
+	}
+
+	var encoded []byte
+	var err error
+	if marshaler, ok := value.(encoding.BinaryMarshaler); ok {
+		encoded, err = marshaler.MarshalBinary()
+	} else {
+		encoded, err = json.Marshal(value)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Store the time the value was stored in front of it, for stale-while-revalidate
+	data := strconv.AppendInt(nil, time.Now().UnixMilli(), 10)
+	data = append(data, '\n')
+	data = append(data, encoded...)
+
+	_, err = c.client.Do(ctx, "SET", c.key(key), data, "PX", ttl.Milliseconds())
+	return err
+}
@@ -0,0 +1,197 @@
+package apiclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/apiclienttest"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/cache"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+	"github.com/Kinveil/Riot-API-Golang/constants/continent"
+	"github.com/Kinveil/Riot-API-Golang/constants/region"
+)
+
+// agingCache is an LRU whose values are reported as stored age ago, so that tests can make
+// them stale without waiting.
+type agingCache struct {
+	*cache.LRU
+
+	mutex sync.Mutex
+	age   time.Duration
+}
+
+func (c *agingCache) setAge(age time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.age = age
+}
+
+func (c *agingCache) Get(ctx context.Context, key string, dest interface{}) (time.Time, bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	storedAt, ok, err := c.LRU.Get(ctx, key, dest)
+	return storedAt.Add(-c.age), ok, err
+}
+
+// newCachingServer returns a server whose GetSummonerByPuuid responses are named after the number
+// of requests it received, and a client that caches them for an hour, and up to an hour past that.
+func newCachingServer(t *testing.T, c cache.Cache) (*apiclienttest.Server, apiclient.Client) {
+	t.Helper()
+
+	server := apiclienttest.NewServer()
+	t.Cleanup(server.Close)
+
+	var mutex sync.Mutex
+	var requests int
+	server.Handle(ratelimiter.GetSummonerByPuuid, func(apiclienttest.Request) apiclienttest.Response {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		requests++
+		return apiclienttest.Response{Body: fmt.Sprintf(`{"name": "response %d"}`, requests)}
+	})
+
+	client := server.NewClient(apiclient.WithCache(c, apiclient.CacheOptions{
+		TTLs:     map[ratelimiter.MethodID]time.Duration{ratelimiter.GetSummonerByPuuid: time.Hour},
+		MaxStale: time.Hour,
+	}))
+
+	return server, client
+}
+
+// summonerName calls GetSummonerByPuuid and returns the name of the summoner.
+func summonerName(t *testing.T, client apiclient.Client) string {
+	t.Helper()
+
+	summoner, err := client.GetSummonerByPuuid(region.KR, "puuid")
+	if err != nil {
+		t.Fatalf("GetSummonerByPuuid() = %v", err)
+	}
+
+	return summoner.Name
+}
+
+func TestCacheServesFreshResponses(t *testing.T) {
+	server, client := newCachingServer(t, cache.NewLRU(10))
+
+	for i := 0; i < 3; i++ {
+		if name := summonerName(t, client); name != "response 1" {
+			t.Fatalf("call %d returned %q, want the first response", i, name)
+		}
+	}
+
+	// Methods without a TTL are not cached
+	server.SetFixture(ratelimiter.GetMatch, apiclient.Match{})
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetMatch(continent.ASIA, "KR_1"); err != nil {
+			t.Fatalf("GetMatch() = %v", err)
+		}
+	}
+
+	if count := server.Count(ratelimiter.GetSummonerByPuuid); count != 1 {
+		t.Errorf("server received %d GetSummonerByPuuid requests, want 1", count)
+	}
+
+	if count := server.Count(ratelimiter.GetMatch); count != 2 {
+		t.Errorf("server received %d GetMatch requests, want 2", count)
+	}
+}
+
+func TestCacheBypass(t *testing.T) {
+	server, client := newCachingServer(t, cache.NewLRU(10))
+
+	summonerName(t, client)
+
+	// The bypassed call is sent, and its response replaces the cached one
+	if name := summonerName(t, client.WithCacheBypass()); name != "response 2" {
+		t.Errorf("bypassed call returned %q, want the second response", name)
+	}
+
+	if name := summonerName(t, client); name != "response 2" {
+		t.Errorf("call after the bypassed one returned %q, want the second response", name)
+	}
+
+	if count := server.Count(ratelimiter.GetSummonerByPuuid); count != 2 {
+		t.Errorf("server received %d requests, want 2", count)
+	}
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	c := &agingCache{LRU: cache.NewLRU(10)}
+	server, client := newCachingServer(t, c)
+
+	summonerName(t, client)
+
+	// A stale response is only returned to calls that allow it
+	c.setAge(90 * time.Minute)
+
+	if name := summonerName(t, client); name != "response 2" {
+		t.Fatalf("call without stale-while-revalidate returned %q, want a new response", name)
+	}
+
+	if name := summonerName(t, client.WithStaleWhileRevalidate()); name != "response 2" {
+		t.Fatalf("call with stale-while-revalidate returned %q, want the stale response", name)
+	}
+
+	// The stale response is refreshed in the background. The cached responses are made fresh, so
+	// that checking for the refreshed one does not refresh it again
+	c.setAge(0)
+
+	for start := time.Now(); summonerName(t, client) != "response 3"; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the stale response was not refreshed")
+		}
+	}
+
+	// Past MaxStale, responses are not returned even to calls that allow stale responses
+	c.setAge(2 * time.Hour)
+
+	if name := summonerName(t, client.WithStaleWhileRevalidate()); name != "response 4" {
+		t.Errorf("call with stale-while-revalidate past MaxStale returned %q, want a new response", name)
+	}
+
+	if count := server.Count(ratelimiter.GetSummonerByPuuid); count != 4 {
+		t.Errorf("server received %d requests, want 4", count)
+	}
+}
+
+// expiredCache loads an expired value into dest, or a part of a value if err is set, as a cache
+// that cannot decode a stored value might.
+type expiredCache struct {
+	err error
+}
+
+func (c expiredCache) Get(ctx context.Context, key string, dest interface{}) (time.Time, bool, error) {
+	json.Unmarshal([]byte(`{"name": "cached", "summonerLevel": 30}`), dest)
+	return time.Now().Add(-3 * time.Hour), c.err == nil, c.err
+}
+
+func (expiredCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return nil
+}
+
+func TestCacheDoesNotFillUnusedResponses(t *testing.T) {
+	for name, c := range map[string]expiredCache{"expired": {}, "failing": {err: errors.New("cannot decode")}} {
+		t.Run(name, func(t *testing.T) {
+			_, client := newCachingServer(t, c)
+
+			// Values that are not returned are not mixed into the response
+			summoner, err := client.WithStaleWhileRevalidate().GetSummonerByPuuid(region.KR, "puuid")
+			if err != nil {
+				t.Fatalf("GetSummonerByPuuid() = %v", err)
+			}
+
+			if summoner.Name != "response 1" || summoner.SummonerLevel != 0 {
+				t.Errorf("GetSummonerByPuuid() = %+v, want the server's response only", summoner)
+			}
+		})
+	}
+}
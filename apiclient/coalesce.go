@@ -0,0 +1,133 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+)
+
+// coalescer lets concurrent identical calls share a single request to the Riot API.
+type coalescer struct {
+	// methods are the methods whose calls are coalesced. If it is empty, every method's are.
+	methods map[ratelimiter.MethodID]bool
+
+	mutex sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+// coalescedCall is a call whose result is shared with the identical calls made while it was in flight.
+type coalescedCall struct {
+	done     chan struct{}
+	result   reflect.Value
+	response *Response
+	info     ResponseInfo
+	err      error
+}
+
+func newCoalescer(methodIDs []ratelimiter.MethodID) *coalescer {
+	co := &coalescer{
+		methods: make(map[ratelimiter.MethodID]bool, len(methodIDs)),
+		calls:   make(map[string]*coalescedCall),
+	}
+
+	for _, methodID := range methodIDs {
+		co.methods[methodID] = true
+	}
+
+	return co
+}
+
+func (co *coalescer) coalesces(methodID ratelimiter.MethodID) bool {
+	return len(co.methods) == 0 || co.methods[methodID]
+}
+
+// do calls send, unless an identical call with the same key is already in flight, in which
+// case it waits for that call and copies its decoded result into dest. The result is decoded
+// once and shallow copied, so the slices and maps in it are shared between the callers.
+// If info is not nil, it is filled with the metadata of the response that was used.
+func (co *coalescer) do(ctx context.Context, key string, dest interface{}, info *ResponseInfo, send func(dest interface{}, info *ResponseInfo) (*Response, error)) (*Response, error) {
+	if reflect.TypeOf(dest).Kind() != reflect.Ptr {
+		return send(dest, info)
+	}
+
+	for {
+		co.mutex.Lock()
+		call, ok := co.calls[key]
+		if !ok {
+			break
+		}
+
+		co.mutex.Unlock()
+
+		if err := wait(ctx, call.done); err != nil {
+			return nil, err
+		}
+
+		// The call was canceled by its own caller, so send the request again
+		if errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded) {
+			continue
+		}
+
+		if info != nil {
+			*info = call.info
+			info.Coalesced = true
+		}
+
+		if call.err != nil {
+			return nil, call.err
+		}
+
+		destValue := reflect.ValueOf(dest)
+		if destValue.Type() != call.result.Type() {
+			return send(dest, info)
+		}
+
+		destValue.Elem().Set(call.result.Elem())
+		return call.response, nil
+	}
+
+	// Decode into a value of our own, so that the caller cannot modify the shared result
+	call := &coalescedCall{
+		done:   make(chan struct{}),
+		result: reflect.New(reflect.TypeOf(dest).Elem()),
+	}
+
+	co.calls[key] = call
+	co.mutex.Unlock()
+
+	call.response, call.err = send(call.result.Interface(), &call.info)
+
+	co.mutex.Lock()
+	delete(co.calls, key)
+	co.mutex.Unlock()
+	close(call.done)
+
+	if info != nil {
+		*info = call.info
+	}
+
+	if call.err != nil {
+		return nil, call.err
+	}
+
+	reflect.ValueOf(dest).Elem().Set(call.result.Elem())
+	return call.response, nil
+}
+
+// wait waits for done to be closed, returning early with an error if ctx is done.
+func wait(ctx context.Context, done <-chan struct{}) error {
+	if ctx == nil {
+		<-done
+		return nil
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
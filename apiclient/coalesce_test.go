@@ -0,0 +1,130 @@
+package apiclient_test
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/apiclienttest"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+	"github.com/Kinveil/Riot-API-Golang/constants/continent"
+)
+
+// coalescedResult is the result of a GetMatch call made while another identical call was in flight.
+type coalescedResult struct {
+	match *apiclient.Match
+	info  apiclient.ResponseInfo
+	err   error
+}
+
+// getMatchConcurrently makes n identical GetMatch calls while the server holds the first request,
+// then lets the server respond with response and returns the results of the calls.
+func getMatchConcurrently(t *testing.T, n int, response apiclienttest.Response) (*apiclienttest.Server, []coalescedResult) {
+	t.Helper()
+
+	server := apiclienttest.NewServer()
+	t.Cleanup(server.Close)
+
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server.Handle(ratelimiter.GetMatch, func(apiclienttest.Request) apiclienttest.Response {
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+
+		<-release
+		return response
+	})
+
+	client := server.NewClient(apiclient.WithCoalescing(ratelimiter.GetMatch))
+	results := make([]coalescedResult, n)
+
+	var started, finished sync.WaitGroup
+	call := func(i int) {
+		defer finished.Done()
+
+		result := &results[i]
+		result.match, result.err = client.WithResponseInfo(&result.info).GetMatch(continent.AMERICAS, "NA1_1")
+	}
+
+	finished.Add(1)
+	go call(0)
+
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the first call was not sent")
+	}
+
+	// The other calls are made while the first one is in flight
+	started.Add(n - 1)
+	finished.Add(n - 1)
+	for i := 1; i < n; i++ {
+		go func(i int) {
+			started.Done()
+			call(i)
+		}(i)
+	}
+
+	// Let the calls that started reach the coalescer before the first one finishes
+	started.Wait()
+	time.Sleep(50 * time.Millisecond)
+
+	close(release)
+	finished.Wait()
+
+	return server, results
+}
+
+func TestCoalescedCallsShareTheResult(t *testing.T) {
+	const n = 10
+	server, results := getMatchConcurrently(t, n, apiclienttest.Response{
+		Body: apiclient.Match{Metadata: apiclient.MatchMetadata{MatchID: "NA1_1", Participants: []string{"a", "b"}}},
+	})
+
+	if count := server.Count(ratelimiter.GetMatch); count != 1 {
+		t.Errorf("server received %d requests, want 1", count)
+	}
+
+	var coalesced int
+	for i, result := range results {
+		if result.err != nil || result.match.Metadata.MatchID != "NA1_1" || len(result.match.Metadata.Participants) != 2 {
+			t.Errorf("call %d = %+v, %v, want match NA1_1", i, result.match, result.err)
+		}
+
+		if result.info.Coalesced {
+			coalesced++
+		}
+	}
+
+	if coalesced != n-1 {
+		t.Errorf("%d calls were coalesced, want %d", coalesced, n-1)
+	}
+
+	// Each caller has its own copy of the result
+	results[0].match.Metadata.MatchID = "modified"
+	if results[1].match.Metadata.MatchID != "NA1_1" {
+		t.Error("modifying a coalesced result modified another caller's")
+	}
+}
+
+func TestCoalescedCallsShareTheError(t *testing.T) {
+	const n = 10
+	server, results := getMatchConcurrently(t, n, apiclienttest.Response{StatusCode: http.StatusServiceUnavailable})
+
+	// Server errors from the Match API are not retried by default, so a single request is sent
+	if count := server.Count(ratelimiter.GetMatch); count != 1 {
+		t.Errorf("server received %d requests, want 1", count)
+	}
+
+	for i, result := range results {
+		var requestErr *apiclient.RequestError
+		if !errors.Is(result.err, apiclient.ErrServiceUnavailable) || !errors.As(result.err, &requestErr) || requestErr.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("call %d = %v, want %v", i, result.err, apiclient.ErrServiceUnavailable)
+		}
+	}
+}
@@ -1,7 +1,13 @@
 package apiclient
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
 )
 
 // Error is a custom error type used by the API to signal http error responses
@@ -83,3 +89,99 @@ var (
 		http.StatusRequestTimeout:       ErrRequestTimeout,
 	}
 )
+
+// maxErrorBodySize is the number of bytes of an error response's body kept in a RequestError.
+const maxErrorBodySize = 64 << 10
+
+// RequestError is returned for calls that did not receive a successful response. It carries
+// the details of the call, and of the response if one was received.
+//
+// Err is the Error above for the response's status code, or ErrUnknown for other status codes,
+// so errors.Is(err, ErrNotFound) still works. Comparing the error with == (err == ErrNotFound)
+// does not, since the error is a *RequestError. If no response was received, Err is the error
+// that prevented it, such as a network error, a context error or ratelimiter.ErrClosed.
+type RequestError struct {
+	Region   string
+	MethodID ratelimiter.MethodID
+	URL      string
+
+	// Retries is the number of times the call was retried.
+	Retries int
+
+	// StatusCode, Header and Body are those of the response, or zero if no response was received.
+	// Body is truncated to 64 KiB.
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	// Message is the message in the body of Riot's error response, e.g.
+	// "Data not found - spectator game info isn't found", if there is one.
+	Message string
+
+	// RetryAfter is the Retry-After header of the response, or 0 if there is none.
+	RetryAfter time.Duration
+
+	Err error
+}
+
+func (e *RequestError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("apiclient: %s %s: %v", e.Region, e.MethodID, e.Err)
+	}
+
+	if e.Message == "" {
+		return fmt.Sprintf("apiclient: %s %s: %d %v", e.Region, e.MethodID, e.StatusCode, e.Err)
+	}
+
+	return fmt.Sprintf("apiclient: %s %s: %d %v: %s", e.Region, e.MethodID, e.StatusCode, e.Err, e.Message)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// newRequestError returns the error of a request that did not receive a response.
+func newRequestError(req *ratelimiter.APIRequest, err error) *RequestError {
+	return &RequestError{
+		Region:   req.Region,
+		MethodID: req.MethodID,
+		URL:      req.URL,
+		Retries:  req.Retries,
+		Err:      err,
+	}
+}
+
+// newResponseError returns the error of a request that received an unsuccessful response.
+func newResponseError(req *ratelimiter.APIRequest, response *Response) *RequestError {
+	e := newRequestError(req, ErrUnknown)
+	e.StatusCode = response.StatusCode
+	e.Header = response.Header
+
+	if err, ok := StatusToError[response.StatusCode]; ok {
+		e.Err = err
+	}
+
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	// Copy the body, since its buffer is reused once the call returns
+	truncated := response.Body
+	if len(truncated) > maxErrorBodySize {
+		truncated = truncated[:maxErrorBodySize]
+	}
+
+	e.Body = append([]byte(nil), truncated...)
+
+	var body struct {
+		Status struct {
+			Message string `json:"message"`
+		} `json:"status"`
+	}
+
+	if json.Unmarshal(e.Body, &body) == nil {
+		e.Message = body.Status.Message
+	}
+
+	return e
+}
@@ -0,0 +1,142 @@
+package apiclient_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/apiclienttest"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+	"github.com/Kinveil/Riot-API-Golang/constants/region"
+)
+
+// noRetries makes failed calls return their first error.
+var noRetries = apiclient.WithRetryPolicy(ratelimiter.RetryPolicyFunc(func(ratelimiter.RetryAttempt) (bool, time.Duration) {
+	return false, 0
+}))
+
+// getSummonerError returns the error of a GetSummonerByPuuid call answered with response.
+func getSummonerError(t *testing.T, response apiclienttest.Response) error {
+	t.Helper()
+
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	server.Handle(ratelimiter.GetSummonerByPuuid, func(apiclienttest.Request) apiclienttest.Response {
+		return response
+	})
+
+	_, err := server.NewClient(noRetries).GetSummonerByPuuid(region.KR, "puuid")
+	return err
+}
+
+func TestRequestErrorMatchesStatusErrors(t *testing.T) {
+	for statusCode, want := range apiclient.StatusToError {
+		err := getSummonerError(t, apiclienttest.Response{StatusCode: statusCode})
+
+		var requestErr *apiclient.RequestError
+		if !errors.Is(err, want) || !errors.As(err, &requestErr) || requestErr.StatusCode != statusCode {
+			t.Errorf("GetSummonerByPuuid() answered with %d = %v, want a RequestError for %v", statusCode, err, want)
+		}
+	}
+
+	// Other status codes match ErrUnknown
+	if err := getSummonerError(t, apiclienttest.Response{StatusCode: http.StatusTeapot}); !errors.Is(err, apiclient.ErrUnknown) {
+		t.Errorf("GetSummonerByPuuid() answered with 418 = %v, want %v", err, apiclient.ErrUnknown)
+	}
+}
+
+func TestRequestErrorNotFound(t *testing.T) {
+	body := `{"status":{"message":"Data not found - summoner not found","status_code":404}}`
+	err := getSummonerError(t, apiclienttest.Response{StatusCode: http.StatusNotFound, Body: body})
+
+	if !errors.Is(err, apiclient.ErrNotFound) || errors.Is(err, apiclient.ErrForbidden) {
+		t.Fatalf("GetSummonerByPuuid() = %v, want %v", err, apiclient.ErrNotFound)
+	}
+
+	// The error wraps ErrNotFound, so it is not equal to it
+	if err == error(apiclient.ErrNotFound) {
+		t.Errorf("GetSummonerByPuuid() = %v, want a RequestError", err)
+	}
+
+	var requestErr *apiclient.RequestError
+	if !errors.As(err, &requestErr) {
+		t.Fatalf("GetSummonerByPuuid() = %v, want a RequestError", err)
+	}
+
+	if requestErr.Message != "Data not found - summoner not found" || string(requestErr.Body) != body {
+		t.Errorf("RequestError Message = %q, Body = %q, want Riot's message and body", requestErr.Message, requestErr.Body)
+	}
+
+	if requestErr.Region != "KR" || requestErr.MethodID != ratelimiter.GetSummonerByPuuid || requestErr.Retries != 0 {
+		t.Errorf("RequestError = %+v, want the region and method of the call", requestErr)
+	}
+}
+
+func TestRequestErrorForbidden(t *testing.T) {
+	err := getSummonerError(t, apiclienttest.Response{
+		StatusCode: http.StatusForbidden,
+		Body:       `{"status":{"message":"Forbidden","status_code":403}}`,
+	})
+
+	var requestErr *apiclient.RequestError
+	if !errors.Is(err, apiclient.ErrForbidden) || !errors.As(err, &requestErr) || requestErr.Message != "Forbidden" {
+		t.Errorf("GetSummonerByPuuid() = %v, want a RequestError for %v", err, apiclient.ErrForbidden)
+	}
+}
+
+func TestRequestErrorRetryAfter(t *testing.T) {
+	err := getSummonerError(t, apiclienttest.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": {"7"}, "X-Rate-Limit-Type": {"service"}},
+	})
+
+	var requestErr *apiclient.RequestError
+	if !errors.Is(err, apiclient.ErrRateLimitExceeded) || !errors.As(err, &requestErr) || requestErr.RetryAfter != 7*time.Second {
+		t.Errorf("GetSummonerByPuuid() = %v, want a RequestError for %v with a 7 second RetryAfter", err, apiclient.ErrRateLimitExceeded)
+	}
+}
+
+func TestRequestErrorWrapsNetworkErrors(t *testing.T) {
+	// Nothing listens on the address once the listener is closed, so the connection is refused
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := listener.Addr().String()
+	listener.Close()
+
+	tests := []struct {
+		name    string
+		baseURL string
+		check   func(err error) bool
+	}{
+		{"connection refused", "http://" + addr, func(err error) bool {
+			var opErr *net.OpError
+			return errors.As(err, &opErr)
+		}},
+		{"unresolvable host", "http://riot-api-golang.invalid", func(err error) bool {
+			var dnsErr *net.DNSError
+			return errors.As(err, &dnsErr)
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := apiclient.New("RGAPI-test", apiclient.WithBaseURL(test.baseURL), noRetries)
+			defer client.Close(context.Background())
+
+			_, err := client.GetSummonerByPuuid(region.KR, "puuid")
+
+			var requestErr *apiclient.RequestError
+			if !errors.As(err, &requestErr) || requestErr.StatusCode != 0 || !test.check(requestErr.Err) {
+				t.Errorf("GetSummonerByPuuid() = %v, want a RequestError wrapping the network error", err)
+			}
+		})
+	}
+}
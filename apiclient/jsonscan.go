@@ -0,0 +1,324 @@
+package apiclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// The functions below walk the elements of JSON arrays and the members of JSON objects without
+// decoding them, so that custom UnmarshalJSON methods can decode each element once, straight
+// from the bytes they are given, rather than copying them to json.RawMessage values first.
+// decodeObjectMembers and decodeArrayElements do the same for a json.Decoder, so that a
+// streamed body is decoded one member or element at a time.
+
+var errJSONSyntax = errors.New("apiclient: invalid JSON")
+
+// eachArrayElement calls fn with each element of the JSON array in data, stopping at the
+// first error. A JSON null is an empty array.
+func eachArrayElement(data []byte, fn func(element []byte) error) error {
+	i := skipSpace(data, 0)
+	if isNull(data[i:]) {
+		return nil
+	}
+
+	if i >= len(data) || data[i] != '[' {
+		return errJSONSyntax
+	}
+
+	i = skipSpace(data, i+1)
+	if i < len(data) && data[i] == ']' {
+		return nil
+	}
+
+	for {
+		end, err := valueEnd(data, i)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(data[i:end]); err != nil {
+			return err
+		}
+
+		i = skipSpace(data, end)
+		if i >= len(data) {
+			return errJSONSyntax
+		}
+
+		switch data[i] {
+		case ',':
+			i = skipSpace(data, i+1)
+		case ']':
+			return nil
+		default:
+			return errJSONSyntax
+		}
+	}
+}
+
+// eachObjectMember calls fn with the key and value of each member of the JSON object in data,
+// stopping at the first error. Keys are passed without their quotes and are not unescaped.
+// A JSON null is an empty object.
+func eachObjectMember(data []byte, fn func(key, value []byte) error) error {
+	i := skipSpace(data, 0)
+	if isNull(data[i:]) {
+		return nil
+	}
+
+	if i >= len(data) || data[i] != '{' {
+		return errJSONSyntax
+	}
+
+	i = skipSpace(data, i+1)
+	if i < len(data) && data[i] == '}' {
+		return nil
+	}
+
+	for {
+		if i >= len(data) || data[i] != '"' {
+			return errJSONSyntax
+		}
+
+		keyEnd, err := stringEnd(data, i)
+		if err != nil {
+			return err
+		}
+
+		key := data[i+1 : keyEnd-1]
+
+		i = skipSpace(data, keyEnd)
+		if i >= len(data) || data[i] != ':' {
+			return errJSONSyntax
+		}
+
+		i = skipSpace(data, i+1)
+		end, err := valueEnd(data, i)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(key, data[i:end]); err != nil {
+			return err
+		}
+
+		i = skipSpace(data, end)
+		if i >= len(data) {
+			return errJSONSyntax
+		}
+
+		switch data[i] {
+		case ',':
+			i = skipSpace(data, i+1)
+		case '}':
+			return nil
+		default:
+			return errJSONSyntax
+		}
+	}
+}
+
+// objectString returns the unescaped value of the member of the JSON object in data with the
+// given key, which must be a string or null. As with json.Unmarshal, keys match without regard
+// to case and the last matching member wins. Members of nested objects are ignored.
+func objectString(data []byte, key string) ([]byte, error) {
+	var value []byte
+	err := eachObjectMember(data, func(k, v []byte) error {
+		if !keyMatches(k, key) {
+			return nil
+		}
+
+		if isNull(v) {
+			value = nil
+			return nil
+		}
+
+		s, err := unquote(v)
+		value = s
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// keyMatches reports whether a key passed by eachObjectMember matches name as json.Unmarshal
+// matches keys to struct fields, after unescaping it and without regard to case.
+func keyMatches(key []byte, name string) bool {
+	if bytes.IndexByte(key, '\\') >= 0 {
+		var unescaped string
+		if json.Unmarshal(append(append([]byte{'"'}, key...), '"'), &unescaped) != nil {
+			return false
+		}
+
+		return nameMatches(unescaped, name)
+	}
+
+	return nameMatches(string(key), name)
+}
+
+// nameMatches reports whether an unescaped key, such as one passed by decodeObjectMembers,
+// matches name as json.Unmarshal matches keys to struct fields, without regard to case.
+func nameMatches(key, name string) bool {
+	return key == name || strings.EqualFold(key, name)
+}
+
+// unquote returns the content of a JSON string, unescaped. Strings without escapes are returned
+// without copying them.
+func unquote(value []byte) ([]byte, error) {
+	if len(value) >= 2 && value[0] == '"' && bytes.IndexByte(value, '\\') < 0 {
+		return value[1 : len(value)-1], nil
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return nil, err
+	}
+
+	return []byte(s), nil
+}
+
+// valueEnd returns the index just past the JSON value that starts at data[i].
+func valueEnd(data []byte, i int) (int, error) {
+	if i >= len(data) {
+		return 0, errJSONSyntax
+	}
+
+	switch data[i] {
+	case '"':
+		return stringEnd(data, i)
+	case '{', '[':
+		depth := 0
+		for i < len(data) {
+			switch data[i] {
+			case '"':
+				end, err := stringEnd(data, i)
+				if err != nil {
+					return 0, err
+				}
+
+				i = end
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1, nil
+				}
+			}
+
+			i++
+		}
+
+		return 0, errJSONSyntax
+	default:
+		// Numbers, booleans and null end at the next delimiter
+		start := i
+		for i < len(data) {
+			switch data[i] {
+			case ',', '}', ']', ' ', '\t', '\r', '\n':
+				if i == start {
+					return 0, errJSONSyntax
+				}
+
+				return i, nil
+			}
+
+			i++
+		}
+
+		return i, nil
+	}
+}
+
+// stringEnd returns the index just past the JSON string that starts at data[i].
+func stringEnd(data []byte, i int) (int, error) {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+
+	return 0, errJSONSyntax
+}
+
+func skipSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+		default:
+			return i
+		}
+	}
+
+	return i
+}
+
+func isNull(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("null"))
+}
+
+// decodeObjectMembers reads the JSON object at the decoder's position, calling fn with each key.
+// fn must decode the member's value from dec, or skip it with skipValue. A JSON null is an empty object.
+func decodeObjectMembers(dec *json.Decoder, fn func(key string) error) error {
+	open, err := dec.Token()
+	if err != nil || open == nil {
+		return err
+	}
+
+	if open != json.Delim('{') {
+		return errJSONSyntax
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		if err := fn(key.(string)); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+// decodeArrayElements reads the JSON array at the decoder's position, calling fn for each element.
+// fn must decode the element from dec. null reports whether the value was a JSON null, which
+// has no elements, so that callers can tell it apart from an empty array as json.Unmarshal does.
+func decodeArrayElements(dec *json.Decoder, fn func() error) (null bool, err error) {
+	open, err := dec.Token()
+	if err != nil || open == nil {
+		return open == nil && err == nil, err
+	}
+
+	if open != json.Delim('[') {
+		return false, errJSONSyntax
+	}
+
+	for dec.More() {
+		if err := fn(); err != nil {
+			return false, err
+		}
+	}
+
+	_, err = dec.Token()
+	return false, err
+}
+
+// skipValue reads the JSON value at the decoder's position without decoding it.
+func skipValue(dec *json.Decoder) error {
+	var value json.RawMessage
+	return dec.Decode(&value)
+}
@@ -0,0 +1,229 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestEachArrayElement(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{data: `[]`},
+		{data: ` [ ] `},
+		{data: `null`},
+		{data: `[1, "a", true, null, -2.5e3]`, want: []string{`1`, `"a"`, `true`, `null`, `-2.5e3`}},
+		{data: `[{"type": [1, {"type": "a"}]}, [[]]]`, want: []string{`{"type": [1, {"type": "a"}]}`, `[[]]`}},
+		{data: `["a\"]", "\\", "[{"]`, want: []string{`"a\"]"`, `"\\"`, `"[{"`}},
+		{data: "[\n\t1\r\n]", want: []string{`1`}},
+		{data: ``, wantErr: true},
+		{data: `[`, wantErr: true},
+		{data: `[1`, wantErr: true},
+		{data: `[1,]`, wantErr: true},
+		{data: `[1 2]`, wantErr: true},
+		{data: `["a]`, wantErr: true},
+		{data: `[{"a": 1]`, wantErr: true},
+		{data: `{}`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		var elements []string
+		err := eachArrayElement([]byte(test.data), func(element []byte) error {
+			elements = append(elements, string(element))
+			return nil
+		})
+
+		if (err != nil) != test.wantErr || !reflect.DeepEqual(elements, test.want) && !test.wantErr {
+			t.Errorf("eachArrayElement(%s) = %q, %v, want %q", test.data, elements, err, test.want)
+		}
+	}
+}
+
+func TestEachObjectMember(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    [][2]string
+		wantErr bool
+	}{
+		{data: `{}`},
+		{data: `null`},
+		{data: `{"a": 1, "b": null}`, want: [][2]string{{`a`, `1`}, {`b`, `null`}}},
+		{data: `{"a": {"type": [1, {"b": "}"}]}}`, want: [][2]string{{`a`, `{"type": [1, {"b": "}"}]}`}}},
+		{data: `{"a\"b": "c\\", "type": []}`, want: [][2]string{{`a\"b`, `"c\\"`}, {`type`, `[]`}}},
+		{data: `{"a": 1, "a": 2}`, want: [][2]string{{`a`, `1`}, {`a`, `2`}}},
+		{data: ``, wantErr: true},
+		{data: `{`, wantErr: true},
+		{data: `{"a"}`, wantErr: true},
+		{data: `{"a":}`, wantErr: true},
+		{data: `{"a": 1,}`, wantErr: true},
+		{data: `{a: 1}`, wantErr: true},
+		{data: `{"a": 1`, wantErr: true},
+		{data: `{"a: 1}`, wantErr: true},
+		{data: `[]`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		var members [][2]string
+		err := eachObjectMember([]byte(test.data), func(key, value []byte) error {
+			members = append(members, [2]string{string(key), string(value)})
+			return nil
+		})
+
+		if (err != nil) != test.wantErr || !reflect.DeepEqual(members, test.want) && !test.wantErr {
+			t.Errorf("eachObjectMember(%s) = %q, %v, want %q", test.data, members, err, test.want)
+		}
+	}
+}
+
+func TestObjectString(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{data: `{"type": "CHAMPION_KILL"}`, want: "CHAMPION_KILL"},
+		{data: `{"killerId": 1, "type": "WARD_KILL", "wardType": "SIGHT_WARD"}`, want: "WARD_KILL"},
+		{data: `{"position": {"type": "ITEM_SOLD"}, "type": "PAUSE_END"}`, want: "PAUSE_END"},
+		{data: `{"position": {"type": "ITEM_SOLD"}, "damage": [{"type": "OTHER"}]}`},
+		{data: `{"type": "CHAMPION\u005fKILL"}`, want: "CHAMPION_KILL"},
+		{data: `{"type": "a\"b\\"}`, want: `a"b\`},
+		{data: `{"typ\u0065": "LEVEL_UP"}`, want: "LEVEL_UP"},
+		{data: `{"Type": "LEVEL_UP"}`, want: "LEVEL_UP"},
+		{data: `{"type": "LEVEL_UP", "type": "ITEM_UNDO"}`, want: "ITEM_UNDO"},
+		{data: `{"type": null}`},
+		{data: `{"types": "LEVEL_UP"}`},
+		{data: `null`},
+		{data: `{"type": 1}`, wantErr: true},
+		{data: `{"type": ["LEVEL_UP"]}`, wantErr: true},
+		{data: `{"type": "LEVEL_UP"`, wantErr: true},
+		{data: `["type", "LEVEL_UP"]`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		value, err := objectString([]byte(test.data), "type")
+		if (err != nil) != test.wantErr || string(value) != test.want {
+			t.Errorf("objectString(%s) = %q, %v, want %q", test.data, value, err, test.want)
+		}
+	}
+}
+
+// oldMatchTimelineFrame is decoded as MatchTimelineFrame was before it was scanned, through a
+// temporary struct with json.RawMessage events, to check that scanning decodes frames the same way.
+type oldMatchTimelineFrame MatchTimelineFrame
+
+func (m *oldMatchTimelineFrame) UnmarshalJSON(data []byte) error {
+	temp := &struct {
+		Timestamp         int                                      `json:"timestamp"`
+		ParticipantFrames map[string]MatchTimelineParticipantFrame `json:"participantFrames"`
+		Events            []json.RawMessage                        `json:"events"`
+	}{}
+
+	if err := json.Unmarshal(data, temp); err != nil {
+		return err
+	}
+
+	m.Timestamp = temp.Timestamp
+
+	for _, rawMsg := range temp.Events {
+		var typeHolder struct {
+			Type MatchTimelineFrameEventType `json:"type"`
+		}
+
+		if err := json.Unmarshal(rawMsg, &typeHolder); err != nil {
+			return err
+		}
+
+		event := newTimelineEvent(typeHolder.Type)
+		if event == nil {
+			continue
+		}
+
+		if err := json.Unmarshal(rawMsg, event); err != nil {
+			return err
+		}
+
+		m.Events = append(m.Events, event)
+	}
+
+	// The map's order is random, so the participant frames are sorted as they are now
+	m.ParticipantFrames = make([]MatchTimelineParticipantFrame, 0, len(temp.ParticipantFrames))
+	for _, pf := range temp.ParticipantFrames {
+		m.ParticipantFrames = append(m.ParticipantFrames, pf)
+	}
+
+	sort.Slice(m.ParticipantFrames, func(i, j int) bool {
+		return m.ParticipantFrames[i].ParticipantID < m.ParticipantFrames[j].ParticipantID
+	})
+
+	return nil
+}
+
+// checkFrameMatchesOldDecoder checks that a frame is decoded the same way by MatchTimelineFrame
+// and oldMatchTimelineFrame, or that both fail.
+func checkFrameMatchesOldDecoder(t *testing.T, data []byte) {
+	t.Helper()
+
+	var frame MatchTimelineFrame
+	var old oldMatchTimelineFrame
+	err := json.Unmarshal(data, &frame)
+	oldErr := json.Unmarshal(data, &old)
+
+	if (err != nil) != (oldErr != nil) {
+		t.Errorf("json.Unmarshal(%.200s) = %v, old decoder = %v", data, err, oldErr)
+		return
+	}
+
+	if err == nil && !reflect.DeepEqual(frame, MatchTimelineFrame(old)) {
+		t.Errorf("json.Unmarshal(%.200s) = %+v, old decoder = %+v", data, frame, MatchTimelineFrame(old))
+	}
+}
+
+func TestMatchTimelineFrameMatchesOldDecoder(t *testing.T) {
+	data, err := os.ReadFile("testdata/match_timeline.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var timeline struct {
+		Info struct {
+			Frames []json.RawMessage `json:"frames"`
+		} `json:"info"`
+	}
+
+	if err := json.Unmarshal(data, &timeline); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, frame := range timeline.Info.Frames {
+		checkFrameMatchesOldDecoder(t, frame)
+	}
+
+	for _, frame := range []string{
+		`{}`,
+		`null`,
+		`{"timestamp": 60000, "participantFrames": null, "events": null}`,
+		`{"participantFrames": {}, "events": []}`,
+		`{"participantFrames": {"2": {"participantId": 2}, "1": {"participantId": 1, "xp": 3}}}`,
+		`{"events": [{"type": "CHAMPION_KILL", "victimDamageDealt": [{"type": "OTHER", "name": "a\"b"}], "killerId": 3}]}`,
+		`{"events": [{"position": {"type": "ITEM_SOLD"}, "type": "WARD_PLACED"}, {"x": {"type": "ITEM_SOLD"}}]}`,
+		`{"events": [{"type": "NEW_EVENT", "itemId": 1}, {"type": null}, {}]}`,
+		`{"events": [{"type": "ITEM_SOLD", "itemId": 1}, {"type": "PAUSE_END", "realmID": "x\\"}]}`,
+		`{"events": [{"type": "CHAMPION\u005fKILL", "killerId": 1}, {"typ\u0065": "LEVEL_UP", "level": 2}]}`,
+		`{"Timestamp": 1, "EVENTS": [{"TYPE": "LEVEL_UP", "level": 2}]}`,
+		`{"events": [{"type": "LEVEL_UP", "type": "ITEM_UNDO", "afterId": 1}]}`,
+		`{"events": [{"type": 1}]}`,
+		`{"events": [{"type": "LEVEL_UP", "level": "2"}]}`,
+		`{"events": {}}`,
+		`{"participantFrames": []}`,
+		`{"timestamp": "1"}`,
+		`{"events": [1]}`,
+		`[]`,
+	} {
+		checkFrameMatchesOldDecoder(t, []byte(frame))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"sort"
 	"time"
 
 	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
@@ -748,94 +749,145 @@ const (
 	MatchTimelineEvent_WardType_YellowTrink MatchTimelineEvent_WardType = "YELLOW_TRINKET"
 )
 
-// Need to unmarshal MatchTimelineFrame because ParticipantFrames comes as an object with keys as participant IDs.
+// Need to unmarshal MatchTimelineFrame because ParticipantFrames comes as an object with keys as participant IDs,
+// and each event must be decoded into the type named by its "type" field. The frame is walked member by member,
+// so that each participant frame and event is decoded once, straight from data.
 func (m *MatchTimelineFrame) UnmarshalJSON(data []byte) error {
-	// Define a temporary struct with the same fields as MatchTimelineFrame
-	temp := &struct {
-		Timestamp         int                                      `json:"timestamp"`
-		ParticipantFrames map[string]MatchTimelineParticipantFrame `json:"participantFrames"`
-		Events            []json.RawMessage                        `json:"events"`
-	}{}
-
-	// Unmarshal data into the temporary struct
-	if err := json.Unmarshal(data, temp); err != nil {
-		return err
-	}
-
-	// Manually assign the values to the MatchTimelineFrame
-	m.Timestamp = temp.Timestamp
+	// A frame without participant frames has an empty slice of them, rather than nil
+	m.ParticipantFrames = []MatchTimelineParticipantFrame{}
+
+	return eachObjectMember(data, func(key, value []byte) error {
+		switch {
+		case keyMatches(key, "timestamp"):
+			return json.Unmarshal(value, &m.Timestamp)
+		case keyMatches(key, "participantFrames"):
+			m.ParticipantFrames = m.ParticipantFrames[:0]
+			err := eachObjectMember(value, func(_, participantFrame []byte) error {
+				m.ParticipantFrames = append(m.ParticipantFrames, MatchTimelineParticipantFrame{})
+				return json.Unmarshal(participantFrame, &m.ParticipantFrames[len(m.ParticipantFrames)-1])
+			})
+
+			// Sort the participant frames by participant ID, rather than by their keys in the object
+			sort.Slice(m.ParticipantFrames, func(i, j int) bool {
+				return m.ParticipantFrames[i].ParticipantID < m.ParticipantFrames[j].ParticipantID
+			})
 
-	for _, rawMsg := range temp.Events {
-		// Unmarshal the type field to determine the event type
-		var typeHolder struct {
-			Type MatchTimelineFrameEventType `json:"type"`
-		}
-		if err := json.Unmarshal(rawMsg, &typeHolder); err != nil {
 			return err
+		case keyMatches(key, "events"):
+			m.Events = nil
+			return eachArrayElement(value, func(element []byte) error {
+				eventType, err := objectString(element, "type")
+				if err != nil {
+					return err
+				}
+
+				event := newTimelineEvent(MatchTimelineFrameEventType(eventType))
+				if event == nil {
+					return nil // Skip unknown event types
+				}
+
+				if err := json.Unmarshal(element, event); err != nil {
+					return err
+				}
+
+				m.Events = append(m.Events, event)
+				return nil
+			})
 		}
 
-		var event interface{}
-		switch typeHolder.Type {
-		case AscendedEvent:
-			event = new(MatchTimelineEvent_AscendedEvent)
-		case BuildingKill:
-			event = new(MatchTimelineEvent_BuildingKill)
-		case CapturePoint:
-			event = new(MatchTimelineEvent_CapturePoint)
-		case ChampionKill:
-			event = new(MatchTimelineEvent_ChampionKill)
-		case ChampionSpecialKill:
-			event = new(MatchTimelineEvent_ChampionSpecialKill)
-		case ChampionTransform:
-			event = new(MatchTimelineEvent_ChampionTransform)
-		case DragonSoulGiven:
-			event = new(MatchTimelineEvent_DragonSoulGiven)
-		case EliteMonsterKill:
-			event = new(MatchTimelineEvent_EliteMonsterKill)
-		case GameEnd:
-			event = new(MatchTimelineEvent_GameEnd)
-		case ItemDestroyed:
-			event = new(MatchTimelineEvent_ItemDestroyed)
-		case ItemPurchased:
-			event = new(MatchTimelineEvent_ItemPurchased)
-		case ItemSold:
-			event = new(MatchTimelineEvent_ItemSold)
-		case ItemUndo:
-			event = new(MatchTimelineEvent_ItemUndo)
-		case LevelUp:
-			event = new(MatchTimelineEvent_LevelUp)
-		case ObjectiveBountyFinish:
-			event = new(MatchTimelineEvent_ObjectiveBountyFinish)
-		case ObjectiveBountyPreStart:
-			event = new(MatchTimelineEvent_ObjectiveBountyPreStart)
-		case PauseEnd:
-			event = new(MatchTimelineEvent_PauseEnd)
-		case PoroKingSummon:
-			event = new(MatchTimelineEvent_PoroKingSummon)
-		case SkillLevelUp:
-			event = new(MatchTimelineEvent_SkillLevelUp)
-		case TurretPlateDestroyed:
-			event = new(MatchTimelineEvent_TurretPlateDestroyed)
-		case WardKill:
-			event = new(MatchTimelineEvent_WardKill)
-		case WardPlaced:
-			event = new(MatchTimelineEvent_WardPlaced)
-		default:
-			continue // Skip unknown event types
+		return nil
+	})
+}
+
+// decodeStream decodes a streamed timeline one frame at a time, so that only the largest frame
+// is held in memory at once, rather than the whole timeline.
+func (m *MatchTimeline) decodeStream(dec *json.Decoder) error {
+	return decodeObjectMembers(dec, func(key string) error {
+		switch {
+		case nameMatches(key, "metadata"):
+			return dec.Decode(&m.Metadata)
+		case nameMatches(key, "info"):
+			return m.Info.decodeStream(dec)
 		}
 
-		// Unmarshal the event
-		if err := json.Unmarshal(rawMsg, event); err != nil {
+		return skipValue(dec)
+	})
+}
+
+func (m *MatchTimelineInfo) decodeStream(dec *json.Decoder) error {
+	return decodeObjectMembers(dec, func(key string) error {
+		switch {
+		case nameMatches(key, "frameInterval"):
+			return dec.Decode(&m.FrameInterval)
+		case nameMatches(key, "frames"):
+			m.Frames = []MatchTimelineFrame{}
+			null, err := decodeArrayElements(dec, func() error {
+				m.Frames = append(m.Frames, MatchTimelineFrame{})
+				return dec.Decode(&m.Frames[len(m.Frames)-1])
+			})
+
+			if null {
+				m.Frames = nil
+			}
+
 			return err
+		case nameMatches(key, "gameId"):
+			return dec.Decode(&m.GameID)
+		case nameMatches(key, "participants"):
+			return dec.Decode(&m.Participants)
 		}
 
-		m.Events = append(m.Events, event)
-	}
-
-	// Convert map to slice
-	m.ParticipantFrames = make([]MatchTimelineParticipantFrame, 0, len(temp.ParticipantFrames))
-	for _, pf := range temp.ParticipantFrames {
-		m.ParticipantFrames = append(m.ParticipantFrames, pf)
+		return skipValue(dec)
+	})
+}
+
+// newTimelineEvent returns a pointer to a new event of the given type, or nil if the type is unknown.
+func newTimelineEvent(eventType MatchTimelineFrameEventType) interface{} {
+	switch eventType {
+	case AscendedEvent:
+		return new(MatchTimelineEvent_AscendedEvent)
+	case BuildingKill:
+		return new(MatchTimelineEvent_BuildingKill)
+	case CapturePoint:
+		return new(MatchTimelineEvent_CapturePoint)
+	case ChampionKill:
+		return new(MatchTimelineEvent_ChampionKill)
+	case ChampionSpecialKill:
+		return new(MatchTimelineEvent_ChampionSpecialKill)
+	case ChampionTransform:
+		return new(MatchTimelineEvent_ChampionTransform)
+	case DragonSoulGiven:
+		return new(MatchTimelineEvent_DragonSoulGiven)
+	case EliteMonsterKill:
+		return new(MatchTimelineEvent_EliteMonsterKill)
+	case GameEnd:
+		return new(MatchTimelineEvent_GameEnd)
+	case ItemDestroyed:
+		return new(MatchTimelineEvent_ItemDestroyed)
+	case ItemPurchased:
+		return new(MatchTimelineEvent_ItemPurchased)
+	case ItemSold:
+		return new(MatchTimelineEvent_ItemSold)
+	case ItemUndo:
+		return new(MatchTimelineEvent_ItemUndo)
+	case LevelUp:
+		return new(MatchTimelineEvent_LevelUp)
+	case ObjectiveBountyFinish:
+		return new(MatchTimelineEvent_ObjectiveBountyFinish)
+	case ObjectiveBountyPreStart:
+		return new(MatchTimelineEvent_ObjectiveBountyPreStart)
+	case PauseEnd:
+		return new(MatchTimelineEvent_PauseEnd)
+	case PoroKingSummon:
+		return new(MatchTimelineEvent_PoroKingSummon)
+	case SkillLevelUp:
+		return new(MatchTimelineEvent_SkillLevelUp)
+	case TurretPlateDestroyed:
+		return new(MatchTimelineEvent_TurretPlateDestroyed)
+	case WardKill:
+		return new(MatchTimelineEvent_WardKill)
+	case WardPlaced:
+		return new(MatchTimelineEvent_WardPlaced)
 	}
 
 	return nil
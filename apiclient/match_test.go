@@ -0,0 +1,148 @@
+package apiclient_test
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/apiclienttest"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+	"github.com/Kinveil/Riot-API-Golang/constants/continent"
+)
+
+// readTimeline returns testdata/match_timeline.json, an anonymized timeline of a 35 minute game
+// laid out as Riot sends it: 36 frames of 10 participants, with about 1200 events of 20 types,
+// one of which (FEAT_UPDATE) the client does not decode.
+func readTimeline(tb testing.TB) []byte {
+	tb.Helper()
+
+	data, err := os.ReadFile("testdata/match_timeline.json")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return data
+}
+
+// newTimelineServer returns a server that serves the timeline without rate limiting the calls.
+func newTimelineServer(tb testing.TB, timeline []byte) *apiclienttest.Server {
+	server := apiclienttest.NewServer()
+	tb.Cleanup(server.Close)
+
+	// The limits are sent to the client, so that it learns them from the first response
+	unlimited := ratelimiter.Window{Limit: 1 << 30, Duration: time.Second}
+	server.SetAppRateLimit(unlimited)
+	server.SetMethodRateLimit(ratelimiter.GetMatchTimeline, unlimited)
+	server.SetFixture(ratelimiter.GetMatchTimeline, timeline)
+	return server
+}
+
+// passThrough is a middleware that does nothing, so that bodies are read before they are decoded.
+func passThrough(next apiclient.Handler) apiclient.Handler {
+	return next
+}
+
+func TestGetMatchTimeline(t *testing.T) {
+	data := readTimeline(t)
+	server := newTimelineServer(t, data)
+
+	var want apiclient.MatchTimeline
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without middlewares the body is decoded as it is read, and with them once it is read
+	for _, client := range []apiclient.Client{server.NewClient(), server.NewClient(apiclient.WithMiddleware(passThrough))} {
+		timeline, err := client.GetMatchTimeline(continent.AMERICAS, "NA1_1")
+		if err != nil {
+			t.Fatalf("GetMatchTimeline() = %v", err)
+		}
+
+		if !reflect.DeepEqual(*timeline, want) {
+			t.Errorf("GetMatchTimeline() differs from json.Unmarshal()")
+		}
+	}
+
+	if len(want.Info.Frames) != 36 || want.Info.Frames[0].ParticipantFrames[9].ParticipantID != 10 {
+		t.Errorf("decoded %d frames, want 36 with their participant frames in order", len(want.Info.Frames))
+	}
+
+	// Each event is decoded into its type, in order, and only the unknown types are skipped
+	var raw struct {
+		Info struct {
+			Frames []struct {
+				Events []struct {
+					Type string `json:"type"`
+				} `json:"events"`
+			} `json:"frames"`
+		} `json:"info"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, frame := range raw.Info.Frames {
+		var types []string
+		for _, event := range frame.Events {
+			if event.Type != "FEAT_UPDATE" {
+				types = append(types, event.Type)
+			}
+		}
+
+		if got := eventTypes(want.Info.Frames[i].Events); !reflect.DeepEqual(got, types) {
+			t.Errorf("frame %d has events %q, want %q", i, got, types)
+		}
+	}
+}
+
+// eventTypes returns the Type field of each event.
+func eventTypes(events []interface{}) []string {
+	var types []string
+	for _, event := range events {
+		types = append(types, reflect.ValueOf(event).Elem().FieldByName("Type").String())
+	}
+
+	return types
+}
+
+func BenchmarkMatchTimelineUnmarshal(b *testing.B) {
+	data := readTimeline(b)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		var timeline apiclient.MatchTimeline
+		if err := json.Unmarshal(data, &timeline); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetMatchTimeline(b *testing.B) {
+	b.Run("Streamed", func(b *testing.B) {
+		benchmarkGetMatchTimeline(b)
+	})
+
+	b.Run("Buffered", func(b *testing.B) {
+		benchmarkGetMatchTimeline(b, apiclient.WithMiddleware(passThrough))
+	})
+}
+
+func benchmarkGetMatchTimeline(b *testing.B, opts ...apiclient.Option) {
+	data := readTimeline(b)
+	client := newTimelineServer(b, data).NewClient(opts...)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := client.GetMatchTimeline(continent.AMERICAS, "NA1_1"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+)
+
+// Call is a call to the Riot API, as seen by middlewares. A middleware can change the call
+// before passing it on, e.g. to add headers or a deadline.
+type Call struct {
+	Context  context.Context
+	Region   string
+	MethodID ratelimiter.MethodID
+	URL      string
+
+	// Header holds the headers sent with the request, in addition to the API key.
+	// They replace the User-Agent set with WithUserAgent.
+	Header http.Header
+}
+
+// Response is the response to a Call. If its StatusCode is 200, Body is decoded into the
+// result of the call. Otherwise, the call fails with a *RequestError.
+//
+// Body is read into a buffer that is reused once the call returns, so middlewares that keep
+// it after returning must copy it.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	buffer *bytes.Buffer // the pooled buffer holding Body, if it was read by the client
+	stream io.ReadCloser // the unread body, if it is decoded as it is read
+}
+
+// Handler sends a Call and returns its response. It returns an error only if no response
+// was received; responses with an error status code are returned as they are. A call whose
+// handler returns neither a response nor an error fails with ErrUnknown.
+type Handler func(call *Call) (*Response, error)
+
+// Middleware wraps the Handler that sends each call. It can inspect or change the call before
+// calling next, inspect or change the response after, or return a response or error of its
+// own without calling next.
+//
+// Calls served from the response cache, and calls that share the response of an identical call
+// with WithCoalescing, do not go through the middlewares.
+type Middleware func(next Handler) Handler
+
+// chain wraps the handler with the middlewares. The first middleware is the outermost, so it
+// sees the call first and the response last.
+func chain(middlewares []Middleware, handler Handler) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
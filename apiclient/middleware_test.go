@@ -0,0 +1,189 @@
+package apiclient_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/apiclienttest"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/cache"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+	"github.com/Kinveil/Riot-API-Golang/constants/region"
+)
+
+func TestMiddlewareWithoutResponse(t *testing.T) {
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	client := server.NewClient(apiclient.WithMiddleware(func(next apiclient.Handler) apiclient.Handler {
+		return func(call *apiclient.Call) (*apiclient.Response, error) {
+			return nil, nil
+		}
+	}))
+
+	var requestErr *apiclient.RequestError
+	_, err := client.GetSummonerByPuuid(region.KR, "puuid")
+	if !errors.As(err, &requestErr) || !errors.Is(err, apiclient.ErrUnknown) || requestErr.MethodID != ratelimiter.GetSummonerByPuuid {
+		t.Fatalf("GetSummonerByPuuid() = %v, want a RequestError for %v", err, apiclient.ErrUnknown)
+	}
+
+	if count := server.Count(""); count != 0 {
+		t.Errorf("server received %d requests, want 0", count)
+	}
+}
+
+// recordingMiddleware appends "<name> call" and "<name> response" to log around the rest of the chain.
+func recordingMiddleware(name string, mutex *sync.Mutex, log *[]string) apiclient.Middleware {
+	record := func(entry string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		*log = append(*log, entry)
+	}
+
+	return func(next apiclient.Handler) apiclient.Handler {
+		return func(call *apiclient.Call) (*apiclient.Response, error) {
+			record(name + " call")
+			response, err := next(call)
+			record(name + " response")
+			return response, err
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(ratelimiter.GetSummonerByPuuid, apiclient.Summoner{Name: "Faker"})
+
+	var mutex sync.Mutex
+	var log []string
+	client := server.NewClient(
+		apiclient.WithMiddleware(recordingMiddleware("a", &mutex, &log), recordingMiddleware("b", &mutex, &log)),
+		apiclient.WithMiddleware(recordingMiddleware("c", &mutex, &log)),
+	)
+
+	if _, err := client.GetSummonerByPuuid(region.KR, "puuid"); err != nil {
+		t.Fatalf("GetSummonerByPuuid() = %v", err)
+	}
+
+	want := []string{"a call", "b call", "c call", "c response", "b response", "a response"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("middleware calls = %q, want %q", log, want)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(ratelimiter.GetSummonerByPuuid, apiclient.Summoner{Name: "Faker"})
+
+	var mutex sync.Mutex
+	var log []string
+	client := server.NewClient(apiclient.WithMiddleware(
+		func(next apiclient.Handler) apiclient.Handler {
+			return func(call *apiclient.Call) (*apiclient.Response, error) {
+				return &apiclient.Response{StatusCode: http.StatusOK, Body: []byte(`{"name": "Stub"}`)}, nil
+			}
+		},
+		recordingMiddleware("inner", &mutex, &log),
+	))
+
+	// The response of the middleware is decoded, and neither the later middlewares nor the server see the call
+	summoner, err := client.GetSummonerByPuuid(region.KR, "puuid")
+	if err != nil || summoner.Name != "Stub" {
+		t.Fatalf("GetSummonerByPuuid() = %+v, %v, want the middleware's response", summoner, err)
+	}
+
+	if len(log) != 0 || server.Count("") != 0 {
+		t.Errorf("inner middleware calls = %q and server requests = %d, want none", log, server.Count(""))
+	}
+
+	// A middleware's error is returned as it is
+	errStub := errors.New("stub")
+	client = server.NewClient(apiclient.WithMiddleware(func(next apiclient.Handler) apiclient.Handler {
+		return func(call *apiclient.Call) (*apiclient.Response, error) {
+			return nil, errStub
+		}
+	}))
+
+	if _, err := client.GetSummonerByPuuid(region.KR, "puuid"); err != errStub {
+		t.Errorf("GetSummonerByPuuid() = %v, want %v", err, errStub)
+	}
+}
+
+func TestMiddlewareModifiesResponse(t *testing.T) {
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(ratelimiter.GetSummonerByPuuid, apiclient.Summoner{Name: "Faker", SummonerLevel: 30})
+
+	rename := apiclient.WithMiddleware(func(next apiclient.Handler) apiclient.Handler {
+		return func(call *apiclient.Call) (*apiclient.Response, error) {
+			response, err := next(call)
+			if err != nil {
+				return nil, err
+			}
+
+			response.Body = bytes.Replace(response.Body, []byte("Faker"), []byte("Hide on bush"), 1)
+			return response, nil
+		}
+	})
+
+	summoner, err := server.NewClient(rename).GetSummonerByPuuid(region.KR, "puuid")
+	if err != nil || summoner.Name != "Hide on bush" || summoner.SummonerLevel != 30 {
+		t.Fatalf("GetSummonerByPuuid() = %+v, %v, want the modified response", summoner, err)
+	}
+
+	// Changing the status code makes the call fail with the error of the new status
+	notFound := apiclient.WithMiddleware(func(next apiclient.Handler) apiclient.Handler {
+		return func(call *apiclient.Call) (*apiclient.Response, error) {
+			response, err := next(call)
+			if err != nil {
+				return nil, err
+			}
+
+			response.StatusCode = http.StatusNotFound
+			return response, nil
+		}
+	})
+
+	if _, err := server.NewClient(notFound).GetSummonerByPuuid(region.KR, "puuid"); !errors.Is(err, apiclient.ErrNotFound) {
+		t.Errorf("GetSummonerByPuuid() = %v, want %v", err, apiclient.ErrNotFound)
+	}
+}
+
+func TestMiddlewareSkippedForCachedCalls(t *testing.T) {
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(ratelimiter.GetSummonerByPuuid, apiclient.Summoner{Name: "Faker"})
+
+	var mutex sync.Mutex
+	var log []string
+	client := server.NewClient(
+		apiclient.WithMiddleware(recordingMiddleware("a", &mutex, &log)),
+		apiclient.WithCache(cache.NewLRU(10), apiclient.CacheOptions{
+			TTLs: map[ratelimiter.MethodID]time.Duration{ratelimiter.GetSummonerByPuuid: time.Hour},
+		}),
+	)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetSummonerByPuuid(region.KR, "puuid"); err != nil {
+			t.Fatalf("GetSummonerByPuuid() = %v", err)
+		}
+	}
+
+	// Only the call that was sent went through the middleware
+	want := []string{"a call", "a response"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("middleware calls = %q, want %q", log, want)
+	}
+}
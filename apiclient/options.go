@@ -0,0 +1,183 @@
+package apiclient
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/cache"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/telemetry"
+)
+
+// HostResolver returns the base URL that requests for the given region or continent are sent to.
+type HostResolver func(regionOrContinent HostProvider) string
+
+// Option configures a Client created with New.
+type Option func(*client)
+
+// WithHTTPClient sets the HTTP client used to send requests to the Riot API.
+// This can be used to route traffic through a proxy or a custom http.RoundTripper.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *client) {
+		c.ratelimiter.SetHTTPClient(httpClient)
+	}
+}
+
+// WithHostResolver overrides the host that each region or continent resolves to.
+// By default, requests are sent to the host returned by HostProvider.Host().
+func WithHostResolver(resolver HostResolver) Option {
+	return func(c *client) {
+		c.hostResolver = resolver
+	}
+}
+
+// WithBaseURL sends the requests for every region and continent to the given base URL,
+// e.g. a local stand-in server such as "http://localhost:8080".
+func WithBaseURL(baseURL string) Option {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return WithHostResolver(func(HostProvider) string {
+		return baseURL
+	})
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *client) {
+		c.ratelimiter.SetUserAgent(userAgent)
+	}
+}
+
+// WithTimeout sets the default timeout for each call. The timeout is only applied
+// when the call's context does not already have a deadline.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *client) {
+		c.timeout = timeout
+	}
+}
+
+// WithRateLimitBackend sets the backend that stores the rate limit state.
+// Use a shared backend, such as ratelimiter.RedisBackend, to coordinate the
+// rate limits of several processes that use the same API key.
+func WithRateLimitBackend(backend ratelimiter.Backend) Option {
+	return func(c *client) {
+		c.ratelimiter.SetBackend(backend)
+	}
+}
+
+// WithStarvationTimeout sets how long a request can wait on a rate limit before it is
+// served ahead of requests with a higher priority. By default, requests are always
+// served in order of priority.
+func WithStarvationTimeout(starvationTimeout time.Duration) Option {
+	return func(c *client) {
+		c.ratelimiter.SetStarvationTimeout(starvationTimeout)
+	}
+}
+
+// WithMetrics sets the Metrics that record request latencies, status codes,
+// queue wait times and 429 responses, labeled by region and MethodID.
+func WithMetrics(metrics telemetry.Metrics) Option {
+	return func(c *client) {
+		c.ratelimiter.SetMetrics(metrics)
+	}
+}
+
+// WithAPIKeys replaces the client's API key with a pool of keys, such as separate keys
+// for each product and backup keys. Each key has its own rate limits, and calls are
+// balanced across the keys that can serve their method. Use Client.WithAPIKey to pin
+// calls to a key.
+func WithAPIKeys(keys ...ratelimiter.APIKey) Option {
+	return func(c *client) {
+		c.ratelimiter.SetAPIKeys(keys)
+	}
+}
+
+// WithRetryPolicy sets the policy that decides whether failed calls are retried, and how
+// long to wait before retrying them. By default, ratelimiter.DefaultRetryPolicy is used.
+// Use Client.WithRetryPolicy to override it for some calls.
+func WithRetryPolicy(policy ratelimiter.RetryPolicy) Option {
+	return func(c *client) {
+		c.ratelimiter.SetRetryPolicy(policy)
+	}
+}
+
+// WithCoalescing makes concurrent identical calls to the given methods share a single request
+// and its decoded result, which saves rate limit slots when the same match or summoner is
+// requested by several goroutines at once. If no methods are given, calls to every method
+// are coalesced. The shared results are shallow copies, so their slices and maps must not be modified.
+func WithCoalescing(methodIDs ...ratelimiter.MethodID) Option {
+	return func(c *client) {
+		c.coalescer = newCoalescer(methodIDs)
+	}
+}
+
+// WithCache caches the decoded responses of the methods in options.TTLs, keyed by URL and API key,
+// so that repeated calls do not use rate limit slots. Use cache.NewLRU for an in-memory cache, or
+// cache.NewRedis to share the cache between processes.
+func WithCache(responseCache cache.Cache, options CacheOptions) Option {
+	return func(c *client) {
+		c.cache = newResponseCache(responseCache, options)
+	}
+}
+
+// WithMiddleware adds middlewares that wrap every call sent to the Riot API. Middlewares run in
+// the order they are given, after those of earlier WithMiddleware options, so the first one sees
+// each call first and its response last.
+//
+// Calls served from the response cache set with WithCache, and calls that share the response of
+// an identical call in flight with WithCoalescing, return without going through the middlewares,
+// so middlewares that must see every call, e.g. to count or audit them, should not be combined
+// with those options.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(c *client) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// WithCircuitBreaker enables a circuit breaker for each region and continent. After a run of
+// server errors or timeouts, calls to the region fail right away with ratelimiter.ErrCircuitOpen
+// until probe calls show that it has recovered.
+func WithCircuitBreaker(options ratelimiter.BreakerOptions) Option {
+	return func(c *client) {
+		c.ratelimiter.SetCircuitBreaker(options)
+	}
+}
+
+// WithMaxQueueDepth limits how many calls can wait for a rate limit at once. Calls beyond
+// the limit fail right away with ratelimiter.ErrQueueFull. By default, the queue is unbounded.
+func WithMaxQueueDepth(maxQueueDepth int) Option {
+	return func(c *client) {
+		c.ratelimiter.SetMaxQueueDepth(maxQueueDepth)
+	}
+}
+
+// WithStateStore restores the rate limit state saved in the store, and saves it every
+// saveInterval and when the client is closed, so that a restarted process does not have
+// to learn the rate limits again. If the state cannot be restored, the client starts
+// with the default limits, and the error is sent to the observer set with WithObserver
+// as a ratelimiter.EventStateStoreError event.
+func WithStateStore(store ratelimiter.StateStore, saveInterval time.Duration) Option {
+	return func(c *client) {
+		c.ratelimiter.SetStateStore(store, saveInterval)
+	}
+}
+
+// WithObserver sets the Observer notified when calls are throttled, retried or rate limited,
+// when Riot reports new rate limits, and when a call is forbidden, which usually means the API
+// key has expired. If async is true, the observer is called from a separate goroutine, and
+// events are dropped if it falls more than 1024 events behind. Stats reports how many were dropped.
+func WithObserver(observer ratelimiter.Observer, async bool) Option {
+	return func(c *client) {
+		c.ratelimiter.SetObserver(observer, async)
+	}
+}
+
+// WithTracer sets the Tracer used to trace each call, the time it spends waiting on rate limits,
+// and each HTTP request sent for it.
+func WithTracer(tracer telemetry.Tracer) Option {
+	return func(c *client) {
+		c.tracer = tracer
+		c.ratelimiter.SetTracer(tracer)
+	}
+}
@@ -0,0 +1,87 @@
+package apiclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/apiclienttest"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+	"github.com/Kinveil/Riot-API-Golang/constants/region"
+)
+
+func TestWithUserAgent(t *testing.T) {
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(ratelimiter.GetSummonerByPuuid, apiclient.Summoner{Name: "Faker"})
+
+	if _, err := server.NewClient(apiclient.WithUserAgent("my-app/1.0")).GetSummonerByPuuid(region.KR, "puuid"); err != nil {
+		t.Fatalf("GetSummonerByPuuid() = %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].Header.Get("User-Agent") != "my-app/1.0" {
+		t.Errorf("requests = %+v, want 1 request with the User-Agent", requests)
+	}
+}
+
+// countingTransport counts the requests it sends with the default transport.
+type countingTransport struct {
+	requests int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.requests, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(ratelimiter.GetSummonerByPuuid, apiclient.Summoner{Name: "Faker"})
+
+	transport := &countingTransport{}
+	summoner, err := server.NewClient(apiclient.WithHTTPClient(&http.Client{Transport: transport})).GetSummonerByPuuid(region.KR, "puuid")
+	if err != nil || summoner.Name != "Faker" {
+		t.Fatalf("GetSummonerByPuuid() = %+v, %v, want Faker", summoner, err)
+	}
+
+	if requests := atomic.LoadInt32(&transport.requests); requests != 1 || server.Count(ratelimiter.GetSummonerByPuuid) != 1 {
+		t.Errorf("transport sent %d requests and server received %d, want 1", requests, server.Count(ratelimiter.GetSummonerByPuuid))
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(ratelimiter.GetSummonerByPuuid, apiclient.Summoner{Name: "Faker"})
+	server.Inject(apiclienttest.Fault{MethodID: ratelimiter.GetSummonerByPuuid, Latency: 200 * time.Millisecond})
+
+	client := server.NewClient(apiclient.WithTimeout(50*time.Millisecond), noRetries)
+
+	// The timeout applies to calls whose context has no deadline
+	start := time.Now()
+	if _, err := client.GetSummonerByPuuid(region.KR, "puuid"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetSummonerByPuuid() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if elapsed := time.Since(start); elapsed >= 200*time.Millisecond {
+		t.Errorf("GetSummonerByPuuid() returned after %v, want it to time out after 50ms", elapsed)
+	}
+
+	// A deadline of the call's context replaces the timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	summoner, err := client.WithContext(ctx).GetSummonerByPuuid(region.KR, "puuid")
+	if err != nil || summoner.Name != "Faker" {
+		t.Errorf("GetSummonerByPuuid() with a deadline = %+v, %v, want Faker", summoner, err)
+	}
+}
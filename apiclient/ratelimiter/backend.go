@@ -0,0 +1,58 @@
+package ratelimiter
+
+import (
+	"context"
+	"time"
+)
+
+// Window is a single rate limit window, e.g. 20 requests every 1 second.
+type Window struct {
+	Limit    int
+	Count    int
+	Duration time.Duration
+
+	// ResetAt is when the window's Count resets, or the zero time if the window has not started.
+	// It is only set in a BucketState; Obtain and Update ignore it.
+	ResetAt time.Time
+}
+
+// BucketState is a snapshot of the rate limit state of a bucket.
+// The Count of each window is the number of slots currently taken.
+type BucketState struct {
+	Windows      []Window
+	BlockedUntil time.Time
+}
+
+// Backend stores the rate limit state of every bucket. A bucket is either the
+// application limit of a region, or the limit of a single method in a region.
+//
+// The default backend keeps its state in memory. A shared backend, such as the
+// RedisBackend, lets several processes using the same API key coordinate their limits.
+//
+// A slot obtained with Obtain stays taken until its window ends, unless it is given
+// back with Release because the request was never sent.
+type Backend interface {
+	// Obtain blocks until a request can be made in every window of the bucket.
+	// If the bucket has no state yet, it is created with the initial windows.
+	Obtain(ctx context.Context, bucket string, initial []Window) error
+
+	// TryObtain takes a slot in every window of the bucket without blocking. If a window
+	// is full, no slot is taken and it returns how long to wait until there is room.
+	TryObtain(bucket string, initial []Window) (time.Duration, error)
+
+	// Release gives back a slot obtained for a request that was never sent.
+	Release(bucket string) error
+
+	// Update applies the windows reported by Riot for a request that was sent.
+	// Windows are identified by their duration, and windows that are not reported are removed.
+	Update(bucket string, windows []Window) error
+
+	// BlockedUntil returns the time until which no requests should be made in the bucket.
+	BlockedUntil(bucket string) (time.Time, error)
+
+	// Block prevents requests from being made in the bucket until the given time.
+	Block(bucket string, until time.Time) error
+
+	// State returns a snapshot of the bucket's state.
+	State(bucket string) (BucketState, error)
+}
@@ -0,0 +1,248 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter/clock"
+)
+
+// BreakerState is the state of a region's circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every request through.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen fails every request with ErrCircuitOpen.
+	BreakerOpen
+
+	// BreakerHalfOpen lets a few probe requests through to find out whether the region has recovered.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerOptions configures the circuit breakers that stop requests to a region or continent
+// while it is failing, e.g. during a platform incident.
+type BreakerOptions struct {
+	// FailureThreshold is the number of consecutive server errors or timeouts that opens
+	// the breaker. Defaults to 5.
+	FailureThreshold int
+
+	// OpenDuration is how long the breaker stays open before it lets probe requests through.
+	// Defaults to 30 seconds.
+	OpenDuration time.Duration
+
+	// Probes is the number of probe requests let through while half-open. The breaker
+	// closes once they all succeed, and opens again if any fails. Defaults to 1.
+	Probes int
+
+	// OnStateChange is called when a region's breaker changes state, if set.
+	// It is called synchronously, so it must not block.
+	OnStateChange func(region string, from, to BreakerState)
+}
+
+// ErrCircuitOpen is returned for requests to a region whose circuit breaker is open.
+// Until is when the breaker will let probe requests through.
+type ErrCircuitOpen struct {
+	Region string
+	Until  time.Time
+}
+
+func (e ErrCircuitOpen) Error() string {
+	return fmt.Sprintf("ratelimiter: circuit breaker for %s is open until %s", e.Region, e.Until.Format(time.RFC3339))
+}
+
+// breaker is the circuit breaker of a single region.
+type breaker struct {
+	mutex     sync.Mutex
+	region    string
+	options   *BreakerOptions
+	clock     clock.Clock
+	state     BreakerState
+	failures  int
+	openUntil time.Time
+
+	// probes is the number of probe requests let through since the breaker half-opened,
+	// and succeeded is how many of them have succeeded.
+	probes    int
+	succeeded int
+
+	// changes are the state changes to report to OnStateChange once b.mutex is released.
+	changes [][2]BreakerState
+}
+
+// allow reports whether a request may be sent. If the breaker is half-open, probe is
+// set and the caller must report the request's result with record, or call abandon
+// if it was never sent.
+func (b *breaker) allow() (probe bool, err error) {
+	b.mutex.Lock()
+	defer b.reportChanges()
+	defer b.mutex.Unlock()
+
+	if b.state == BreakerOpen && !b.clock.Now().Before(b.openUntil) {
+		b.probes = 0
+		b.succeeded = 0
+		b.setState(BreakerHalfOpen)
+	}
+
+	switch b.state {
+	case BreakerOpen:
+		return false, ErrCircuitOpen{Region: b.region, Until: b.openUntil}
+	case BreakerHalfOpen:
+		if b.probes >= b.options.Probes {
+			return false, ErrCircuitOpen{Region: b.region, Until: b.openUntil}
+		}
+
+		b.probes++
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// abandon gives back the probe of a request that was never sent.
+func (b *breaker) abandon(probe bool) {
+	if !probe {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// record updates the breaker with the result of a request that was sent.
+func (b *breaker) record(probe, failed bool) {
+	b.mutex.Lock()
+	defer b.reportChanges()
+	defer b.mutex.Unlock()
+
+	if b.state == BreakerHalfOpen {
+		// Results of requests sent before the breaker half-opened are ignored
+		if !probe {
+			return
+		}
+
+		if failed {
+			b.open()
+			return
+		}
+
+		b.succeeded++
+		if b.succeeded >= b.options.Probes {
+			b.failures = 0
+			b.setState(BreakerClosed)
+		}
+
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerClosed && b.failures >= b.options.FailureThreshold {
+		b.open()
+	}
+}
+
+// open opens the breaker. The caller must hold b.mutex.
+func (b *breaker) open() {
+	b.openUntil = b.clock.Now().Add(b.options.OpenDuration)
+	b.setState(BreakerOpen)
+}
+
+// setState changes the breaker's state. The caller must hold b.mutex.
+func (b *breaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+
+	b.changes = append(b.changes, [2]BreakerState{b.state, state})
+	b.state = state
+}
+
+// reportChanges calls OnStateChange for the state changes made while b.mutex was held.
+// It is called without holding b.mutex, so that OnStateChange can call Stats.
+func (b *breaker) reportChanges() {
+	b.mutex.Lock()
+	changes := b.changes
+	b.changes = nil
+	b.mutex.Unlock()
+
+	if b.options.OnStateChange == nil {
+		return
+	}
+
+	for _, change := range changes {
+		b.options.OnStateChange(b.region, change[0], change[1])
+	}
+}
+
+func (b *breaker) currentState() BreakerState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.state
+}
+
+// SetCircuitBreaker enables a circuit breaker for each region and continent. It must be
+// called before Start. Zero fields of options are replaced with their defaults.
+func (rl *RateLimiter) SetCircuitBreaker(options BreakerOptions) {
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = 5
+	}
+
+	if options.OpenDuration <= 0 {
+		options.OpenDuration = 30 * time.Second
+	}
+
+	if options.Probes <= 0 {
+		options.Probes = 1
+	}
+
+	rl.breakerOptions = &options
+}
+
+// breaker returns the circuit breaker of the region, creating it if needed.
+// It returns nil if circuit breakers are not enabled.
+func (rl *RateLimiter) breaker(region string) *breaker {
+	if rl.breakerOptions == nil {
+		return nil
+	}
+
+	rl.breakersMutex.Lock()
+	defer rl.breakersMutex.Unlock()
+
+	b, ok := rl.breakers[region]
+	if !ok {
+		b = &breaker{
+			region:  region,
+			options: rl.breakerOptions,
+			clock:   rl.clock,
+		}
+
+		rl.breakers[region] = b
+	}
+
+	return b
+}
@@ -0,0 +1,208 @@
+package ratelimiter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter/clock"
+)
+
+// stateChanges records the state changes reported to OnStateChange.
+type stateChanges struct {
+	mutex   sync.Mutex
+	changes []string
+}
+
+func (s *stateChanges) record(region string, from, to BreakerState) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.changes = append(s.changes, fmt.Sprintf("%s: %s -> %s", region, from, to))
+}
+
+func (s *stateChanges) get() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string(nil), s.changes...)
+}
+
+// newTestBreaker returns the breaker of NA1, with a fake clock and its state changes recorded.
+func newTestBreaker(options BreakerOptions) (*breaker, *clock.Fake, *stateChanges) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	changes := &stateChanges{}
+	options.OnStateChange = changes.record
+
+	rl := NewRateLimiter(make(chan *APIRequest), "RGAPI-test")
+	rl.SetClock(fake)
+	rl.SetCircuitBreaker(options)
+
+	return rl.breaker("NA1"), fake, changes
+}
+
+// checkAllow checks whether allow lets a request through, and whether it is a probe.
+func checkAllow(t *testing.T, b *breaker, wantProbe, wantAllowed bool) {
+	t.Helper()
+
+	probe, err := b.allow()
+
+	var circuitOpen ErrCircuitOpen
+	if allowed := err == nil; allowed != wantAllowed || probe != wantProbe || (!allowed && !errors.As(err, &circuitOpen)) {
+		t.Fatalf("allow() = %v, %v in state %s, want probe %v, allowed %v", probe, err, b.currentState(), wantProbe, wantAllowed)
+	}
+}
+
+func TestBreakerOpensAndCloses(t *testing.T) {
+	b, fake, changes := newTestBreaker(BreakerOptions{FailureThreshold: 3, OpenDuration: 30 * time.Second})
+
+	// Failures only open the breaker when they are consecutive
+	b.record(false, true)
+	b.record(false, true)
+	b.record(false, false)
+	b.record(false, true)
+	b.record(false, true)
+	checkAllow(t, b, false, true)
+
+	b.record(false, true)
+	if state := b.currentState(); state != BreakerOpen {
+		t.Fatalf("state after 3 failures = %s, want open", state)
+	}
+
+	_, err := b.allow()
+	var circuitOpen ErrCircuitOpen
+	if !errors.As(err, &circuitOpen) || circuitOpen.Region != "NA1" || !circuitOpen.Until.Equal(fake.Now().Add(30*time.Second)) {
+		t.Fatalf("allow() while open = %v, want ErrCircuitOpen until 30 seconds from now", err)
+	}
+
+	fake.Advance(30*time.Second - time.Millisecond)
+	checkAllow(t, b, false, false)
+
+	// Once OpenDuration has passed, one probe is let through
+	fake.Advance(time.Millisecond)
+	checkAllow(t, b, true, true)
+	checkAllow(t, b, false, false)
+
+	b.record(true, false)
+	checkAllow(t, b, false, true)
+
+	want := []string{"NA1: closed -> open", "NA1: open -> half-open", "NA1: half-open -> closed"}
+	if got := changes.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("OnStateChange calls = %q, want %q", got, want)
+	}
+}
+
+func TestBreakerProbes(t *testing.T) {
+	b, fake, _ := newTestBreaker(BreakerOptions{FailureThreshold: 1, OpenDuration: time.Second, Probes: 2})
+
+	b.record(false, true)
+	fake.Advance(time.Second)
+
+	checkAllow(t, b, true, true)
+	checkAllow(t, b, true, true)
+	checkAllow(t, b, false, false)
+
+	// A probe that was never sent is given back
+	b.abandon(true)
+	checkAllow(t, b, true, true)
+
+	// Results of requests sent before the breaker half-opened are ignored
+	b.record(false, true)
+	b.record(true, false)
+	if state := b.currentState(); state != BreakerHalfOpen {
+		t.Fatalf("state after 1 of 2 probes succeeded = %s, want half-open", state)
+	}
+
+	b.record(true, false)
+	if state := b.currentState(); state != BreakerClosed {
+		t.Fatalf("state after 2 of 2 probes succeeded = %s, want closed", state)
+	}
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+	b, fake, changes := newTestBreaker(BreakerOptions{FailureThreshold: 1, OpenDuration: 10 * time.Second})
+
+	b.record(false, true)
+	fake.Advance(10 * time.Second)
+	checkAllow(t, b, true, true)
+
+	// The breaker opens again for another OpenDuration from the failed probe
+	fake.Advance(time.Second)
+	b.record(true, true)
+
+	_, err := b.allow()
+	var circuitOpen ErrCircuitOpen
+	if !errors.As(err, &circuitOpen) || !circuitOpen.Until.Equal(fake.Now().Add(10*time.Second)) {
+		t.Fatalf("allow() after a failed probe = %v, want ErrCircuitOpen until 10 seconds from now", err)
+	}
+
+	want := []string{"NA1: closed -> open", "NA1: open -> half-open", "NA1: half-open -> open"}
+	if got := changes.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("OnStateChange calls = %q, want %q", got, want)
+	}
+}
+
+// breakerState returns the state of NA1's breaker reported by Stats.
+func breakerState(t *testing.T, rl *RateLimiter) BreakerState {
+	t.Helper()
+
+	stats, err := rl.Stats()
+	if err != nil {
+		t.Fatalf("Stats() = %v", err)
+	}
+
+	return stats.Breakers["NA1"]
+}
+
+func TestBreakerCountsServerErrorsButNotRateLimits(t *testing.T) {
+	statuses := make(chan int, 4)
+	rl, server, _ := newFakeClockRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(<-statuses)
+	}, func(rl *RateLimiter) {
+		rl.SetCircuitBreaker(BreakerOptions{FailureThreshold: 2})
+	})
+
+	noRetries := RetryPolicyFunc(func(RetryAttempt) (bool, time.Duration) {
+		return false, 0
+	})
+
+	call := func(status int) *APIResponse {
+		statuses <- status
+		return send(t, rl, &APIRequest{Region: "NA1", MethodID: GetMatch, URL: server.URL, RetryPolicy: noRetries})
+	}
+
+	// A 429 between two server errors resets the count of consecutive failures
+	for _, status := range []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		if res := call(status); res.Err != nil {
+			t.Fatalf("response error = %v", res.Err)
+		} else {
+			res.Response.Body.Close()
+		}
+	}
+
+	if state := breakerState(t, rl); state != BreakerClosed {
+		t.Fatalf("breaker state = %s, want closed", state)
+	}
+
+	if res := call(http.StatusBadGateway); res.Err != nil {
+		t.Fatalf("response error = %v", res.Err)
+	} else {
+		res.Response.Body.Close()
+	}
+
+	if state := breakerState(t, rl); state != BreakerOpen {
+		t.Fatalf("breaker state after 2 consecutive server errors = %s, want open", state)
+	}
+
+	// Requests now fail without being sent
+	res := send(t, rl, &APIRequest{Region: "NA1", MethodID: GetMatch, URL: server.URL, RetryPolicy: noRetries})
+
+	var circuitOpen ErrCircuitOpen
+	if !errors.As(res.Err, &circuitOpen) {
+		t.Errorf("response error = %v, want ErrCircuitOpen", res.Err)
+	}
+}
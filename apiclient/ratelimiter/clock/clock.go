@@ -0,0 +1,55 @@
+// Package clock abstracts the passage of time so that the rate limiter can be tested
+// without waiting in real time.
+package clock
+
+import "time"
+
+// Clock tells the time and creates timers.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single event, like time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is sent when the timer fires.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing. It returns false if the timer has already fired or been stopped.
+	Stop() bool
+}
+
+// Real is the Clock backed by the time package.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+// Since returns the time elapsed since t according to the clock.
+func Since(c Clock, t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Until returns the duration until t according to the clock.
+func Until(c Clock, t time.Time) time.Duration {
+	return t.Sub(c.Now())
+}
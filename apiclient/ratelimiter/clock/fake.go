@@ -0,0 +1,103 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only changes when it is advanced. Timers fire when
+// the clock is advanced past their deadline.
+type Fake struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	deadline time.Time
+	c        chan time.Time
+}
+
+// NewFake returns a Fake clock set to the given time.
+func NewFake(now time.Time) *Fake {
+	return &Fake{
+		now: now,
+	}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	t := &fakeTimer{
+		clock:    f,
+		deadline: f.now.Add(d),
+		c:        make(chan time.Time, 1),
+	}
+
+	if d <= 0 {
+		t.c <- f.now
+		return t
+	}
+
+	f.timers = append(f.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by d and fires the timers whose deadline has passed.
+func (f *Fake) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.now = f.now.Add(d)
+
+	pending := f.timers[:0]
+	for _, t := range f.timers {
+		if t.deadline.After(f.now) {
+			pending = append(pending, t)
+			continue
+		}
+
+		t.c <- f.now
+	}
+
+	f.timers = pending
+}
+
+// Timers returns the number of timers that have not fired or been stopped. Tests can
+// wait for it to reach the expected number before advancing the clock, to be sure that
+// the code under test has started waiting.
+func (f *Fake) Timers() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return len(f.timers)
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	f := t.clock
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for i, other := range f.timers {
+		if other == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
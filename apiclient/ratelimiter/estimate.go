@@ -0,0 +1,182 @@
+package ratelimiter
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter/clock"
+)
+
+// Estimate is the estimated time it would take to send more requests to a method.
+type Estimate struct {
+	// Queued is the number of requests to the method that are already waiting for a slot.
+	Queued int
+
+	// Wait is the estimated time until the last of the requests is sent, after the queued ones.
+	Wait time.Duration
+
+	// Throughput is the number of requests per second the method's and region's limits allow
+	// in the long run, across every key that can serve the method.
+	Throughput float64
+}
+
+// Estimate estimates how long it would take to send n more requests to the method in the region,
+// given the limits learned from Riot, the slots already taken and the requests already queued.
+// Requests to other methods in the region also use up the region's limits, so the estimate is
+// only accurate if they do not change much. Limits that have not been learned yet are assumed
+// to be the initial limits.
+func (rl *RateLimiter) Estimate(region string, methodID MethodID, n int) (Estimate, error) {
+	var keys []*APIKey
+	if len(rl.keys) == 0 {
+		keys = []*APIKey{{Key: rl.apiKey}}
+	} else {
+		for _, key := range rl.keys {
+			if key.canServe(methodID) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		return Estimate{}, ErrNoAPIKey
+	}
+
+	// The requests are assumed to be spread evenly across the keys
+	perKey := (n + len(keys) - 1) / len(keys)
+
+	var estimate Estimate
+	for _, key := range keys {
+		regionQueued, methodQueued := rl.queued(key.Name, region, methodID)
+		estimate.Queued += methodQueued
+
+		regionState, err := rl.backend.State(regionBucket(key.Name, region))
+		if err != nil {
+			return estimate, err
+		}
+
+		methodState, err := rl.backend.State(methodBucket(key.Name, region, methodID))
+		if err != nil {
+			return estimate, err
+		}
+
+		regionWait, regionThroughput := rl.estimateBucket(regionState, initialRegionWindows, regionQueued+perKey)
+		methodWait, methodThroughput := rl.estimateBucket(methodState, initialMethodWindows, methodQueued+perKey)
+
+		if wait := maxDuration(regionWait, methodWait); wait > estimate.Wait {
+			estimate.Wait = wait
+		}
+
+		estimate.Throughput += math.Min(regionThroughput, methodThroughput)
+	}
+
+	return estimate, nil
+}
+
+// queued returns the number of requests waiting for a slot in the region and method buckets of a key.
+func (rl *RateLimiter) queued(keyName, region string, methodID MethodID) (int, int) {
+	rl.countersMutex.Lock()
+	defer rl.countersMutex.Unlock()
+
+	var regionQueued, methodQueued int
+	if counters, ok := rl.bucketCounters[regionBucket(keyName, region)]; ok {
+		regionQueued = int(atomic.LoadInt64(&counters.queued))
+	}
+
+	if counters, ok := rl.bucketCounters[methodBucket(keyName, region, methodID)]; ok {
+		methodQueued = int(atomic.LoadInt64(&counters.queued))
+	}
+
+	return regionQueued, methodQueued
+}
+
+// estimateBucket estimates how long it would take to send n requests in the bucket, and
+// the number of requests per second its windows allow.
+func (rl *RateLimiter) estimateBucket(state BucketState, initial []Window, n int) (time.Duration, float64) {
+	windows := state.Windows
+	if len(windows) == 0 {
+		windows = initial
+	}
+
+	now := rl.clock.Now()
+
+	var start time.Duration
+	if state.BlockedUntil.After(now) {
+		start = state.BlockedUntil.Sub(now)
+	}
+
+	var wait time.Duration
+	throughput := math.Inf(1)
+
+	for _, w := range windows {
+		if w.Limit <= 0 || w.Duration <= 0 {
+			continue
+		}
+
+		throughput = math.Min(throughput, float64(w.Limit)/w.Duration.Seconds())
+
+		// The slots left in the current window are used first, then Limit slots in each later window
+		remaining := n - (w.Limit - w.Count)
+		if remaining <= 0 {
+			continue
+		}
+
+		resetIn := w.Duration
+		if !w.ResetAt.IsZero() {
+			resetIn = clock.Until(rl.clock, w.ResetAt)
+		}
+
+		windowsNeeded := (remaining + w.Limit - 1) / w.Limit
+		if windowWait := resetIn + time.Duration(windowsNeeded-1)*w.Duration; windowWait > wait {
+			wait = windowWait
+		}
+	}
+
+	if math.IsInf(throughput, 1) {
+		throughput = 0
+	}
+
+	return maxDuration(start, wait), throughput
+}
+
+// Pending returns the number of submitted requests that have not received a response for each
+// key in the key pool, by name: the requests that are queued or in flight, and those waiting to
+// be retried. If no key pool is set, the requests are counted under the empty name. Requests
+// blocked in Submit, before a key is selected for them, are counted under the name of the key
+// they are pinned to, or under the empty name.
+func (rl *RateLimiter) Pending() map[string]int {
+	rl.countersMutex.Lock()
+	defer rl.countersMutex.Unlock()
+
+	pending := make(map[string]int)
+	if len(rl.keys) == 0 {
+		pending[""] = 0
+	}
+
+	for _, key := range rl.keys {
+		pending[key.Name] = 0
+	}
+
+	// Every request is counted in its region bucket, so the method buckets are skipped
+	for _, counters := range rl.bucketCounters {
+		if counters.methodID == "" {
+			pending[counters.key] += int(atomic.LoadInt64(&counters.queued) + atomic.LoadInt64(&counters.inFlight))
+		}
+	}
+
+	for keyName, waiting := range rl.waiting {
+		if waiting > 0 {
+			pending[keyName] += waiting
+		}
+	}
+
+	return pending
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+
+	return b
+}
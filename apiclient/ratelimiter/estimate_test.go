@@ -0,0 +1,172 @@
+package ratelimiter
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter/clock"
+)
+
+// newEstimateRateLimiter returns a rate limiter with a fake clock, which is not started.
+func newEstimateRateLimiter() (*RateLimiter, *clock.Fake) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+
+	rl := NewRateLimiter(make(chan *APIRequest), "RGAPI-test")
+	rl.SetClock(fake)
+
+	return rl, fake
+}
+
+// checkEstimate checks the estimate of n more requests to GetMatch in NA1.
+func checkEstimate(t *testing.T, rl *RateLimiter, n int, want Estimate) {
+	t.Helper()
+
+	estimate, err := rl.Estimate("NA1", GetMatch, n)
+	if err != nil || estimate.Queued != want.Queued || estimate.Wait != want.Wait || math.Abs(estimate.Throughput-want.Throughput) > 1e-9 {
+		t.Errorf("Estimate(%d) = %+v, %v, want %+v", n, estimate, err, want)
+	}
+}
+
+func TestEstimateWithInitialLimits(t *testing.T) {
+	rl, _ := newEstimateRateLimiter()
+
+	// The initial limits are 20 requests every second and every 2 minutes in the region, and 5
+	// requests every 10 seconds for the method
+	throughput := 20.0 / 120
+	checkEstimate(t, rl, 5, Estimate{Throughput: throughput})
+	checkEstimate(t, rl, 6, Estimate{Wait: 10 * time.Second, Throughput: throughput})
+	checkEstimate(t, rl, 25, Estimate{Wait: 2 * time.Minute, Throughput: throughput})
+}
+
+func TestEstimateWithLearnedLimits(t *testing.T) {
+	rl, fake := newEstimateRateLimiter()
+
+	rl.backend.Update(regionBucket("", "NA1"), []Window{{Limit: 500, Count: 0, Duration: 10 * time.Second}})
+	rl.backend.Update(methodBucket("", "NA1", GetMatch), []Window{{Limit: 100, Count: 90, Duration: 10 * time.Second}})
+
+	checkEstimate(t, rl, 10, Estimate{Throughput: 10})
+
+	// The rest of the requests wait for the method's window to reset, then for as many windows as they need
+	checkEstimate(t, rl, 11, Estimate{Wait: 10 * time.Second, Throughput: 10})
+	checkEstimate(t, rl, 210, Estimate{Wait: 20 * time.Second, Throughput: 10})
+
+	fake.Advance(4 * time.Second)
+	checkEstimate(t, rl, 11, Estimate{Wait: 6 * time.Second, Throughput: 10})
+
+	// Requests already queued for the method go first
+	regionCounters, methodCounters := rl.counters("", "NA1", GetMatch)
+	addCount(10, &regionCounters.queued, &methodCounters.queued)
+	checkEstimate(t, rl, 1, Estimate{Queued: 10, Wait: 6 * time.Second, Throughput: 10})
+
+	// Nothing is sent until a block ends
+	rl.backend.Block(regionBucket("", "NA1"), fake.Now().Add(time.Minute))
+	checkEstimate(t, rl, 1, Estimate{Queued: 10, Wait: time.Minute, Throughput: 10})
+}
+
+func TestEstimateWithKeyPool(t *testing.T) {
+	rl, _ := newEstimateRateLimiter()
+	rl.SetAPIKeys([]APIKey{
+		{Name: "a", Key: "RGAPI-a"},
+		{Name: "b", Key: "RGAPI-b"},
+		{Name: "tft", Key: "RGAPI-tft", Products: []Product{ProductTFT}},
+	})
+
+	// The requests are spread across the 2 keys that can serve the method, which double the throughput
+	throughput := 2 * 20.0 / 120
+	checkEstimate(t, rl, 10, Estimate{Throughput: throughput})
+	checkEstimate(t, rl, 11, Estimate{Wait: 10 * time.Second, Throughput: throughput})
+
+	rl.SetAPIKeys([]APIKey{{Name: "tft", Key: "RGAPI-tft", Products: []Product{ProductTFT}}})
+	if _, err := rl.Estimate("NA1", GetMatch, 1); err != ErrNoAPIKey {
+		t.Errorf("Estimate() without a key for the method = %v, want %v", err, ErrNoAPIKey)
+	}
+}
+
+// waitForPending waits until Pending returns want.
+func waitForPending(t *testing.T, rl *RateLimiter, want map[string]int) {
+	t.Helper()
+
+	for start := time.Now(); ; time.Sleep(time.Millisecond) {
+		pending := rl.Pending()
+		if reflect.DeepEqual(pending, want) {
+			return
+		}
+
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("Pending() = %v, want %v", pending, want)
+		}
+	}
+}
+
+func TestPendingCountsBlockedSubmits(t *testing.T) {
+	rl := NewRateLimiter(make(chan *APIRequest), "RGAPI-test")
+	rl.SetAPIKeys([]APIKey{{Name: "a", Key: "RGAPI-a"}, {Name: "b", Key: "RGAPI-b"}})
+	defer rl.Close(context.Background())
+
+	// The rate limiter is not started, so Submit blocks. The requests are counted under the
+	// key they are pinned to, or under the empty name until a key is selected for them
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	submitted := make(chan error, 2)
+	for _, key := range []string{"", "b"} {
+		go func(key string) {
+			submitted <- rl.Submit(&APIRequest{Context: ctx, Region: "NA1", MethodID: GetMatch, Key: key, Response: make(chan *APIResponse, 1)})
+		}(key)
+	}
+
+	waitForPending(t, rl, map[string]int{"": 1, "a": 0, "b": 1})
+
+	cancel()
+	for i := 0; i < 2; i++ {
+		if err := <-submitted; err != context.Canceled {
+			t.Errorf("Submit() = %v, want %v", err, context.Canceled)
+		}
+	}
+
+	if pending := rl.Pending(); !reflect.DeepEqual(pending, map[string]int{"a": 0, "b": 0}) {
+		t.Errorf("Pending() after the submits were canceled = %v, want no requests", pending)
+	}
+}
+
+func TestPendingCountsRetries(t *testing.T) {
+	var calls int32
+	inFlight := make(chan struct{})
+	rl, server, fake := newFakeClockRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		select {
+		case <-inFlight:
+			w.Write([]byte("{}"))
+		case <-r.Context().Done():
+		}
+	}, func(rl *RateLimiter) {
+		rl.SetRetryPolicy(RetryPolicyFunc(func(RetryAttempt) (bool, time.Duration) {
+			return true, time.Minute
+		}))
+	})
+
+	// The request is pending while it waits to be retried, and while it is in flight again
+	responses := submit(t, rl, server)
+	waitForTimer(t, fake, responses)
+	waitForPending(t, rl, map[string]int{"": 1})
+
+	fake.Advance(time.Minute)
+	for atomic.LoadInt32(&calls) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	waitForPending(t, rl, map[string]int{"": 1})
+
+	close(inFlight)
+	receive(t, responses)
+	waitForPending(t, rl, map[string]int{"": 0})
+}
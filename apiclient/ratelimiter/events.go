@@ -0,0 +1,204 @@
+package ratelimiter
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType is the kind of an Event.
+type EventType string
+
+const (
+	// EventThrottled is sent when a request had to wait on a rate limit before it was sent.
+	// Wait is how long it waited.
+	EventThrottled EventType = "throttled"
+
+	// EventRetry is sent when a failed request is about to be retried. Wait is the delay
+	// before the retry, and StatusCode or Err tell why the request failed.
+	EventRetry EventType = "retry"
+
+	// EventRateLimited is sent for a 429 response. LimitType is its X-Rate-Limit-Type
+	// header and Wait is its Retry-After header.
+	EventRateLimited EventType = "rate_limited"
+
+	// EventLimitsUpdated is sent when Riot reports limits that differ from the ones in use.
+	// Windows are the new limits, after usage conservation is applied.
+	EventLimitsUpdated EventType = "limits_updated"
+
+	// EventForbidden is sent for a 403 response, which usually means the API key has expired.
+	EventForbidden EventType = "forbidden"
+
+	// EventStateStoreError is sent when the state cannot be restored from the state store
+	// or saved to it periodically. Err is the error. It is not about a request, so only
+	// Time is set besides Err.
+	EventStateStoreError EventType = "state_store_error"
+)
+
+// Event describes something that happened to a request in the rate limiter.
+type Event struct {
+	Type     EventType
+	Time     time.Time
+	Region   string
+	MethodID MethodID
+
+	// Key is the name of the key in the key pool that the request was sent with, if any.
+	Key string
+
+	// URL is the request's URL, with any API key in it redacted.
+	URL string
+
+	Wait       time.Duration
+	Retries    int
+	StatusCode int
+	Err        error
+	LimitType  string
+	Windows    []Window
+}
+
+// Observer is notified of the rate limiter's events.
+type Observer interface {
+	OnEvent(event Event)
+}
+
+// ObserverFunc adapts a function to an Observer.
+type ObserverFunc func(event Event)
+
+func (f ObserverFunc) OnEvent(event Event) {
+	f(event)
+}
+
+// eventBufferSize is the number of events an asynchronous observer can fall behind by
+// before events are dropped.
+const eventBufferSize = 1024
+
+// SetObserver sets the Observer notified of the rate limiter's events. If async is false,
+// the observer is called synchronously by the goroutine handling the request, so it must
+// not block. Otherwise, events are delivered in order by a separate goroutine, and they are
+// dropped if the observer falls more than 1024 events behind, which Stats reports as
+// DroppedEvents. Close waits for the events to be delivered. It must be called at most once,
+// before Start.
+func (rl *RateLimiter) SetObserver(observer Observer, async bool) {
+	rl.observer = observer
+
+	if observer != nil && async {
+		rl.events = make(chan Event, eventBufferSize)
+		rl.eventsDelivered = make(chan struct{})
+		go rl.deliverEvents()
+	}
+}
+
+// deliverEvents calls the asynchronous observer for each event until the rate limiter is closed.
+func (rl *RateLimiter) deliverEvents() {
+	defer close(rl.eventsDelivered)
+
+	for {
+		select {
+		case event := <-rl.events:
+			rl.observer.OnEvent(event)
+		case <-rl.done:
+			// Deliver the events sent before the rate limiter was closed
+			for {
+				select {
+				case event := <-rl.events:
+					rl.observer.OnEvent(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// emit notifies the observer of an event about the request.
+func (rl *RateLimiter) emit(req *APIRequest, key *APIKey, event Event) {
+	if rl.observer == nil {
+		return
+	}
+
+	event.Time = rl.clock.Now()
+	event.Region = req.Region
+	event.MethodID = req.MethodID
+	event.Key = key.Name
+	event.URL = redactURL(req.URL, key.Key)
+
+	rl.notify(event)
+}
+
+// notify sends an event to the observer.
+func (rl *RateLimiter) notify(event Event) {
+	if rl.observer == nil {
+		return
+	}
+
+	if rl.events == nil {
+		rl.observer.OnEvent(event)
+		return
+	}
+
+	select {
+	case rl.events <- event:
+	default:
+		atomic.AddInt64(&rl.droppedEvents, 1)
+	}
+}
+
+// redactURL removes the API key from a URL, whether it is in the api_key query parameter or elsewhere.
+func redactURL(rawURL, apiKey string) string {
+	if apiKey != "" {
+		rawURL = strings.ReplaceAll(rawURL, apiKey, "REDACTED")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	query := u.Query()
+	if value := query.Get("api_key"); value == "" || value == "REDACTED" {
+		return rawURL
+	}
+
+	query.Set("api_key", "REDACTED")
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// limitsTracker remembers the last limits applied to each bucket, to tell when they change.
+type limitsTracker struct {
+	mutex  sync.Mutex
+	limits map[string][]Window
+}
+
+// changed records the bucket's limits and reports whether they differ from the previous ones.
+// The windows' counts are ignored.
+func (t *limitsTracker) changed(bucket string, windows []Window) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.limits == nil {
+		t.limits = make(map[string][]Window)
+	}
+
+	previous := t.limits[bucket]
+	limits := make([]Window, len(windows))
+	for i, w := range windows {
+		limits[i] = Window{Limit: w.Limit, Duration: w.Duration}
+	}
+
+	t.limits[bucket] = limits
+
+	if len(previous) != len(limits) {
+		return true
+	}
+
+	for i := range limits {
+		if previous[i] != limits[i] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,197 @@
+package ratelimiter
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// eventRecorder is an Observer that records the events it is sent.
+type eventRecorder struct {
+	mutex  sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) OnEvent(event Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+// get returns the recorded events, without their time, after checking that they are about a
+// GetMatch request to NA1 sent to url.
+func (r *eventRecorder) get(t *testing.T, url string) []Event {
+	t.Helper()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	events := make([]Event, len(r.events))
+	for i, event := range r.events {
+		if event.Region != "NA1" || event.MethodID != GetMatch || event.URL != url || event.Time.IsZero() {
+			t.Errorf("event %s is about %s %s at %s, sent at %v, want a GetMatch request to NA1 at %s",
+				event.Type, event.Region, event.MethodID, event.URL, event.Time, url)
+		}
+
+		event.Time = time.Time{}
+		events[i] = event
+	}
+
+	return events
+}
+
+// checkEvents checks the types of the events and the fields that are set in want.
+func checkEvents(t *testing.T, got, want []Event) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got events %+v, want %+v", got, want)
+	}
+
+	for i := range want {
+		if got[i].Type != want[i].Type ||
+			got[i].Wait != want[i].Wait ||
+			got[i].Retries != want[i].Retries ||
+			got[i].StatusCode != want[i].StatusCode ||
+			got[i].LimitType != want[i].LimitType ||
+			len(got[i].Windows) != len(want[i].Windows) {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+			continue
+		}
+
+		for j, window := range want[i].Windows {
+			if got[i].Windows[j].Limit != window.Limit || got[i].Windows[j].Duration != window.Duration {
+				t.Errorf("event %d window %d = %+v, want %+v", i, j, got[i].Windows[j], window)
+			}
+		}
+	}
+}
+
+func TestRateLimitedAndRetryEvents(t *testing.T) {
+	var calls int32
+	recorder := &eventRecorder{}
+	rl, server, fake := newFakeClockRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "3")
+			w.Header().Set("X-Rate-Limit-Type", "method")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, func(rl *RateLimiter) {
+		rl.SetObserver(recorder, false)
+		rl.SetRetryPolicy(RetryPolicyFunc(func(attempt RetryAttempt) (bool, time.Duration) {
+			if attempt.StatusCode == http.StatusTooManyRequests {
+				return true, attempt.RetryAfter
+			}
+
+			return attempt.Retries < 2, time.Second
+		}))
+	})
+
+	responses := submit(t, rl, server)
+	waitForTimer(t, fake, responses)
+	fake.Advance(3 * time.Second)
+	waitForTimer(t, fake, responses)
+	fake.Advance(time.Second)
+
+	if res := receive(t, responses); res.Response.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", res.Response.StatusCode)
+	}
+
+	checkEvents(t, recorder.get(t, server.URL), []Event{
+		{Type: EventRateLimited, Wait: 3 * time.Second, StatusCode: http.StatusTooManyRequests, LimitType: "method"},
+		{Type: EventRetry, Wait: 3 * time.Second, Retries: 1, StatusCode: http.StatusTooManyRequests},
+		{Type: EventRetry, Wait: time.Second, Retries: 2, StatusCode: http.StatusServiceUnavailable},
+	})
+}
+
+func TestLimitsUpdatedAndThrottledEvents(t *testing.T) {
+	recorder := &eventRecorder{}
+	rl, server, fake := newFakeClockRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-App-Rate-Limit", "20:1,100:120")
+		w.Header().Set("X-App-Rate-Limit-Count", "1:1,1:120")
+		w.Header().Set("X-Method-Rate-Limit", "2:10")
+		w.Header().Set("X-Method-Rate-Limit-Count", "2:10")
+		w.Write([]byte("{}"))
+	}, func(rl *RateLimiter) {
+		rl.SetObserver(recorder, false)
+	})
+
+	receive(t, submit(t, rl, server))
+
+	// The method's limit is reached, so the second request waits
+	responses := submit(t, rl, server)
+	waitForTimer(t, fake, responses)
+	fake.Advance(10 * time.Second)
+	receive(t, responses)
+
+	// Only the limits that differ from the ones in use are reported, less the margin of one request
+	checkEvents(t, recorder.get(t, server.URL), []Event{
+		{Type: EventLimitsUpdated, LimitType: "application", Windows: []Window{{Limit: 19, Duration: time.Second}, {Limit: 99, Duration: 2 * time.Minute}}},
+		{Type: EventLimitsUpdated, LimitType: "method", Windows: []Window{{Limit: 1, Duration: 10 * time.Second}}},
+		{Type: EventThrottled, Wait: 10 * time.Second},
+	})
+}
+
+func TestForbiddenEvent(t *testing.T) {
+	recorder := &eventRecorder{}
+	rl, server := newTestRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}, func(rl *RateLimiter) {
+		rl.SetObserver(recorder, false)
+	})
+
+	res := send(t, rl, &APIRequest{Region: "NA1", MethodID: GetMatch, URL: server.URL})
+	if res.Err != nil || res.Response.StatusCode != http.StatusForbidden {
+		t.Fatalf("response = %+v, want a 403 response", res)
+	}
+
+	res.Response.Body.Close()
+
+	checkEvents(t, recorder.get(t, server.URL), []Event{{Type: EventForbidden, StatusCode: http.StatusForbidden}})
+}
+
+func TestAsyncObserverDropsEvents(t *testing.T) {
+	received := make(chan Event)
+	release := make(chan struct{})
+	var delivered int32
+
+	rl := NewRateLimiter(make(chan *APIRequest), "RGAPI-test")
+	rl.SetObserver(ObserverFunc(func(event Event) {
+		if atomic.AddInt32(&delivered, 1) == 1 {
+			received <- event
+			<-release
+		}
+	}), true)
+
+	go rl.Start()
+
+	// The observer is blocked on the first event, so the buffer fills up with the next ones
+	rl.notify(Event{Type: EventForbidden})
+	<-received
+
+	for i := 0; i < eventBufferSize+10; i++ {
+		rl.notify(Event{Type: EventForbidden})
+	}
+
+	if stats, err := rl.Stats(); err != nil || stats.DroppedEvents != 10 {
+		t.Errorf("Stats().DroppedEvents = %d, %v, want 10", stats.DroppedEvents, err)
+	}
+
+	close(release)
+
+	// Close waits for the buffered events to be delivered
+	if err := rl.Close(context.Background()); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	if delivered := atomic.LoadInt32(&delivered); delivered != eventBufferSize+1 {
+		t.Errorf("%d events delivered, want %d", delivered, eventBufferSize+1)
+	}
+}
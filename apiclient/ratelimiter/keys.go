@@ -0,0 +1,199 @@
+package ratelimiter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync/atomic"
+)
+
+// Product is a Riot game whose endpoints a key can be restricted to.
+type Product string
+
+const (
+	ProductLoL Product = "lol"
+	ProductTFT Product = "tft"
+	ProductLoR Product = "lor"
+	ProductVAL Product = "val"
+)
+
+// Product returns the product of the method's endpoint, or an empty Product for
+// endpoints that every product's keys can call, such as the Account API.
+//
+// Every method is listed, so that methods added for TFT, LoR or VAL are not routed
+// to LoL keys by default. Unknown methods have an empty Product.
+func (m MethodID) Product() Product {
+	switch m {
+	case GetAccountByPuuid, GetAccountByRiotID:
+		return ""
+	case GetChampionMasteriesBySummonerID, GetChampionMasteryBySummonerIDAndChampionID, GetChampionMasteriesTopBySummonerID,
+		GetChampionMasteryScoreTotalBySummonerID, GetChampionRotations,
+		GetClashPlayersByPuuid, GetClashPlayersBySummonerID, GetClashTeamByID, GetClashTournaments,
+		GetClashTournamentByTeamID, GetClashTournamentByID,
+		GetLeagueExpEntries, GetLeagueEntriesChallenger, GetLeagueEntriesGrandmaster, GetLeagueEntriesMaster,
+		GetLeagueEntries, GetLeagueEntriesByID, GetLeagueEntriesBySummonerID,
+		GetChallengesConfig, GetChallengesPercentiles, GetChallengesConfigByID, GetChallengesLeaderboardsByLevel,
+		GetChallengesPercentilesByID, GetChallengesPlayerDataByPuuid,
+		GetStatusPlatformData,
+		GetMatchlist, GetMatch, GetMatchTimeline,
+		GetSpectatorActiveGameBySummonerID, GetSpectatorFeaturedGames,
+		GetSummonerByRsoPuuid, GetSummonerByAccountID, GetSummonerByName, GetSummonerByPuuid, GetSummonerBySummonerID:
+		return ProductLoL
+	default:
+		return ""
+	}
+}
+
+// APIKey is a key in a key pool. Each key has its own rate limits.
+type APIKey struct {
+	// Name identifies the key. It is used to pin calls to the key and to group its Stats.
+	Name string
+
+	// Key is the API key sent in the X-Riot-Token header.
+	Key string
+
+	// Methods and Products restrict the key to the given methods, or to the methods of the given
+	// products. If both are empty, the key can serve every method.
+	Methods  []MethodID
+	Products []Product
+}
+
+var (
+	// ErrUnknownAPIKey is returned for requests pinned to a key that is not in the key pool.
+	ErrUnknownAPIKey = errors.New("ratelimiter: unknown API key")
+
+	// ErrNoAPIKey is returned for requests whose method cannot be served by any key in the key pool.
+	ErrNoAPIKey = errors.New("ratelimiter: no API key can serve the method")
+)
+
+// canServe reports whether the key's routing rules allow it to serve the method.
+func (k *APIKey) canServe(methodID MethodID) bool {
+	if len(k.Methods) == 0 && len(k.Products) == 0 {
+		return true
+	}
+
+	for _, m := range k.Methods {
+		if m == methodID {
+			return true
+		}
+	}
+
+	if len(k.Products) == 0 {
+		return false
+	}
+
+	product := methodID.Product()
+	if product == "" {
+		return true
+	}
+
+	for _, p := range k.Products {
+		if p == product {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetAPIKeys replaces the API key with a pool of keys. Each key has its own rate limits,
+// and requests are balanced across the keys that can serve their method. It must be
+// called before Start. It panics if a key has no name or two keys have the same name.
+func (rl *RateLimiter) SetAPIKeys(keys []APIKey) {
+	names := make(map[string]bool, len(keys))
+	pool := make([]*APIKey, 0, len(keys))
+
+	for i := range keys {
+		if keys[i].Name == "" {
+			panic("API keys in a key pool must have a name")
+		}
+
+		if names[keys[i].Name] {
+			panic("API keys in a key pool must have unique names")
+		}
+
+		names[keys[i].Name] = true
+
+		key := keys[i]
+		pool = append(pool, &key)
+	}
+
+	rl.keys = pool
+}
+
+// KeyHash returns a hash that identifies the key that requests pinned to the named key are
+// sent with, without revealing it. If name is empty, it identifies the API key, or the key
+// pool if one is set. Responses that contain encrypted IDs can only be shared between
+// requests with the same hash.
+func (rl *RateLimiter) KeyHash(name string) string {
+	hash := sha256.New()
+	if name != "" {
+		for _, key := range rl.keys {
+			if key.Name == name {
+				hash.Write([]byte(key.Key))
+			}
+		}
+	} else if len(rl.keys) == 0 {
+		hash.Write([]byte(rl.apiKey))
+	} else {
+		for _, key := range rl.keys {
+			hash.Write([]byte(key.Key))
+			hash.Write([]byte{0})
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
+
+// selectKey returns the key that serves the request. Requests pinned to a key with
+// APIRequest.Key are always served by it. Otherwise, the key that can serve the method
+// with the fewest queued and in flight requests is chosen, taking turns on ties.
+func (rl *RateLimiter) selectKey(req *APIRequest) (*APIKey, error) {
+	if len(rl.keys) == 0 {
+		if req.Key != "" {
+			return nil, ErrUnknownAPIKey
+		}
+
+		return &APIKey{Key: rl.apiKey}, nil
+	}
+
+	if req.Key != "" {
+		for _, key := range rl.keys {
+			if key.Name == req.Key {
+				return key, nil
+			}
+		}
+
+		return nil, ErrUnknownAPIKey
+	}
+
+	start := int(atomic.AddUint32(&rl.nextKey, 1) % uint32(len(rl.keys)))
+
+	rl.countersMutex.Lock()
+	defer rl.countersMutex.Unlock()
+
+	var selected *APIKey
+	var selectedLoad int64
+	for i := range rl.keys {
+		key := rl.keys[(start+i)%len(rl.keys)]
+		if !key.canServe(req.MethodID) {
+			continue
+		}
+
+		var load int64
+		if counters, ok := rl.bucketCounters[methodBucket(key.Name, req.Region, req.MethodID)]; ok {
+			load = atomic.LoadInt64(&counters.queued) + atomic.LoadInt64(&counters.inFlight)
+		}
+
+		if selected == nil || load < selectedLoad {
+			selected = key
+			selectedLoad = load
+		}
+	}
+
+	if selected == nil {
+		return nil, ErrNoAPIKey
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,176 @@
+package ratelimiter
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestMethodProducts(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "method_ids.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every method ID declared in method_ids.go must have a product, unless it is an Account API method
+	var methods int
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+
+		for _, name := range spec.Names {
+			methods++
+			methodID := MethodID(name.Name)
+			if product := methodID.Product(); product == "" && methodID != GetAccountByPuuid && methodID != GetAccountByRiotID {
+				t.Errorf("%s has no product", methodID)
+			}
+		}
+
+		return false
+	})
+
+	if methods == 0 {
+		t.Error("found no method IDs in method_ids.go")
+	}
+}
+
+func TestSelectKey(t *testing.T) {
+	rl := NewRateLimiter(make(chan *APIRequest), "RGAPI-single")
+	rl.SetAPIKeys([]APIKey{
+		{Name: "lol", Key: "RGAPI-lol", Products: []Product{ProductLoL}},
+		{Name: "tft", Key: "RGAPI-tft", Products: []Product{ProductTFT}},
+		{Name: "match", Key: "RGAPI-match", Methods: []MethodID{GetMatch}},
+	})
+
+	tests := []struct {
+		name     string
+		req      APIRequest
+		want     []string
+		wantErr  error
+		attempts int
+	}{
+		{"pinned", APIRequest{Region: "NA1", MethodID: GetMatch, Key: "tft"}, []string{"tft"}, nil, 3},
+		{"unknown key", APIRequest{Region: "NA1", MethodID: GetMatch, Key: "val"}, nil, ErrUnknownAPIKey, 1},
+		{"product", APIRequest{Region: "NA1", MethodID: GetSummonerByPuuid}, []string{"lol"}, nil, 3},
+		{"product or method", APIRequest{Region: "NA1", MethodID: GetMatch}, []string{"lol", "match"}, nil, 4},
+		{"every product", APIRequest{Region: "NA1", MethodID: GetAccountByPuuid}, []string{"lol", "tft"}, nil, 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected := make(map[string]bool)
+			for i := 0; i < test.attempts; i++ {
+				key, err := rl.selectKey(&test.req)
+				if err != test.wantErr {
+					t.Fatalf("selectKey() = %v, want %v", err, test.wantErr)
+				}
+
+				if key != nil {
+					selected[key.Name] = true
+				}
+			}
+
+			// Keys take turns when they have the same load, so every key that can serve the method is selected
+			if len(selected) != len(test.want) {
+				t.Errorf("selectKey() selected %v, want %v", selected, test.want)
+			}
+
+			for _, name := range test.want {
+				if !selected[name] {
+					t.Errorf("selectKey() selected %v, want %v", selected, test.want)
+				}
+			}
+		})
+	}
+
+	rl.SetAPIKeys([]APIKey{{Name: "tft", Key: "RGAPI-tft", Products: []Product{ProductTFT}}})
+	if _, err := rl.selectKey(&APIRequest{Region: "NA1", MethodID: GetMatch}); err != ErrNoAPIKey {
+		t.Errorf("selectKey() without a key for the method = %v, want %v", err, ErrNoAPIKey)
+	}
+}
+
+func TestSelectKeyWithoutPool(t *testing.T) {
+	rl := NewRateLimiter(make(chan *APIRequest), "RGAPI-single")
+
+	key, err := rl.selectKey(&APIRequest{Region: "NA1", MethodID: GetMatch})
+	if err != nil || key.Key != "RGAPI-single" || key.Name != "" {
+		t.Errorf("selectKey() = %+v, %v, want the API key", key, err)
+	}
+
+	if _, err := rl.selectKey(&APIRequest{Region: "NA1", MethodID: GetMatch, Key: "lol"}); err != ErrUnknownAPIKey {
+		t.Errorf("selectKey() pinned without a key pool = %v, want %v", err, ErrUnknownAPIKey)
+	}
+
+	// SetAPIKey replaces a key pool
+	rl.SetAPIKeys([]APIKey{{Name: "lol", Key: "RGAPI-lol"}})
+	rl.SetAPIKey("RGAPI-new")
+
+	key, err = rl.selectKey(&APIRequest{Region: "NA1", MethodID: GetMatch})
+	if err != nil || key.Key != "RGAPI-new" {
+		t.Errorf("selectKey() after SetAPIKey = %+v, %v, want the new API key", key, err)
+	}
+}
+
+func TestSelectKeyWithFewestRequests(t *testing.T) {
+	rl := NewRateLimiter(make(chan *APIRequest), "")
+	rl.SetAPIKeys([]APIKey{{Name: "a", Key: "RGAPI-a"}, {Name: "b", Key: "RGAPI-b"}})
+
+	// Requests queued or in flight on a key's bucket for the method count towards its load
+	regionCounters, methodCounters := rl.counters("a", "NA1", GetMatch)
+	addCount(2, &regionCounters.queued, &methodCounters.queued)
+	addCount(1, &regionCounters.inFlight, &methodCounters.inFlight)
+
+	_, otherCounters := rl.counters("b", "NA1", GetMatch)
+	addCount(2, &otherCounters.inFlight)
+
+	for i := 0; i < 4; i++ {
+		if key, err := rl.selectKey(&APIRequest{Region: "NA1", MethodID: GetMatch}); err != nil || key.Name != "b" {
+			t.Fatalf("selectKey() = %+v, %v, want the key with fewer requests", key, err)
+		}
+	}
+
+	// Only the method's bucket counts, so other methods take turns on both keys
+	selected := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		key, _ := rl.selectKey(&APIRequest{Region: "NA1", MethodID: GetSummonerByPuuid})
+		selected[key.Name] = true
+	}
+
+	if !selected["a"] || !selected["b"] {
+		t.Errorf("selectKey() selected %v for another method, want both keys", selected)
+	}
+}
+
+func TestRequestsAreBalancedAcrossKeys(t *testing.T) {
+	var mutex sync.Mutex
+	tokens := make(map[string]int)
+	rl, server := newTestRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		tokens[r.Header.Get("X-Riot-Token")]++
+		mutex.Unlock()
+	}, func(rl *RateLimiter) {
+		rl.SetAPIKeys([]APIKey{{Name: "a", Key: "RGAPI-a"}, {Name: "b", Key: "RGAPI-b"}})
+	})
+
+	// Requests sent one at a time take turns on the keys, and a pinned request is sent with its key
+	for _, key := range []string{"", "", "", "", "b"} {
+		res := send(t, rl, &APIRequest{Region: "NA1", MethodID: GetMatch, URL: server.URL, Key: key})
+		if res.Err != nil {
+			t.Fatalf("response error = %v", res.Err)
+		}
+
+		res.Response.Body.Close()
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if tokens["RGAPI-a"] != 2 || tokens["RGAPI-b"] != 3 {
+		t.Errorf("requests sent with each key = %v, want 2 with a and 3 with b", tokens)
+	}
+}
@@ -0,0 +1,354 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter/clock"
+)
+
+// MemoryBackend is a Backend that keeps the rate limit state in memory.
+// It only coordinates the requests of a single process.
+//
+// Each window is a fixed window that starts with the first request made after the
+// previous window ended, like Riot's. Requests waiting on a full window are woken
+// by a single timer when the window resets, rather than one goroutine per request.
+type MemoryBackend struct {
+	mutex   sync.Mutex
+	buckets map[string]*memoryBucket
+	clock   clock.Clock
+}
+
+type memoryBucket struct {
+	mutex        sync.Mutex
+	windows      []*window
+	blockedUntil time.Time
+
+	// changed is closed and replaced whenever the bucket's windows change,
+	// so that waiting requests can check them again.
+	changed chan struct{}
+}
+
+// window is a fixed rate limit window.
+type window struct {
+	limit    int
+	duration time.Duration
+	count    int
+	resetAt  time.Time // The zero time if the window has not started
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		buckets: make(map[string]*memoryBucket),
+		clock:   clock.Real,
+	}
+}
+
+// SetClock sets the clock used to time the windows. It must be called before the backend is used.
+func (b *MemoryBackend) SetClock(c clock.Clock) {
+	b.clock = c
+}
+
+func (b *MemoryBackend) bucket(name string) *memoryBucket {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	bucket, ok := b.buckets[name]
+	if !ok {
+		bucket = &memoryBucket{
+			changed: make(chan struct{}),
+		}
+
+		b.buckets[name] = bucket
+	}
+
+	return bucket
+}
+
+// expire resets the window if it has ended.
+func (w *window) expire(now time.Time) {
+	if !w.resetAt.IsZero() && !now.Before(w.resetAt) {
+		w.count = 0
+		w.resetAt = time.Time{}
+	}
+}
+
+// notify wakes the requests waiting on the bucket. The caller must hold bucket.mutex.
+func (bucket *memoryBucket) notify() {
+	close(bucket.changed)
+	bucket.changed = make(chan struct{})
+}
+
+// reserve takes a slot in every window of the bucket if none of them are full.
+// Otherwise, it returns how long to wait until every window has room.
+// The caller must hold bucket.mutex.
+func (bucket *memoryBucket) reserve(now time.Time) time.Duration {
+	var wait time.Duration
+	for _, w := range bucket.windows {
+		w.expire(now)
+
+		if w.count >= w.limit {
+			if until := w.resetAt.Sub(now); until > wait {
+				wait = until
+			}
+		}
+	}
+
+	if wait > 0 {
+		return wait
+	}
+
+	for _, w := range bucket.windows {
+		if w.resetAt.IsZero() {
+			w.resetAt = now.Add(w.duration)
+		}
+
+		w.count++
+	}
+
+	return 0
+}
+
+// init creates the bucket's windows if it has none. The caller must hold bucket.mutex.
+func (bucket *memoryBucket) init(initial []Window) {
+	// The bucket may have been created without windows, e.g. by BlockedUntil
+	if len(bucket.windows) > 0 {
+		return
+	}
+
+	for _, w := range initial {
+		bucket.windows = append(bucket.windows, &window{
+			limit:    w.Limit,
+			duration: w.Duration,
+		})
+	}
+}
+
+func (b *MemoryBackend) Obtain(ctx context.Context, name string, initial []Window) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	bucket := b.bucket(name)
+
+	for {
+		bucket.mutex.Lock()
+		bucket.init(initial)
+		wait := bucket.reserve(b.clock.Now())
+		changed := bucket.changed
+		bucket.mutex.Unlock()
+
+		if wait == 0 {
+			return nil
+		}
+
+		timer := b.clock.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-changed:
+			timer.Stop()
+		case <-timer.C():
+		}
+	}
+}
+
+func (b *MemoryBackend) TryObtain(name string, initial []Window) (time.Duration, error) {
+	bucket := b.bucket(name)
+
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	bucket.init(initial)
+	return bucket.reserve(b.clock.Now()), nil
+}
+
+func (b *MemoryBackend) Release(name string) error {
+	bucket := b.bucket(name)
+
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	now := b.clock.Now()
+	for _, w := range bucket.windows {
+		w.expire(now)
+
+		if w.count > 0 {
+			w.count--
+		}
+	}
+
+	bucket.notify()
+	return nil
+}
+
+func (b *MemoryBackend) Update(name string, windows []Window) error {
+	bucket := b.bucket(name)
+
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	now := b.clock.Now()
+	updated := make([]*window, 0, len(windows))
+
+	for _, w := range windows {
+		// Match the windows by their duration, since they are identified by it
+		var existing *window
+		for _, other := range bucket.windows {
+			if other.duration == w.Duration {
+				existing = other
+				break
+			}
+		}
+
+		if existing == nil {
+			existing = &window{
+				duration: w.Duration,
+			}
+		}
+
+		existing.expire(now)
+		existing.limit = w.Limit
+
+		// A window must allow at least one request, or requests would wait forever
+		if existing.limit < 1 {
+			existing.limit = 1
+		}
+
+		// Riot's count may include requests made by other clients using the same key
+		if w.Count > existing.count {
+			existing.count = w.Count
+		}
+
+		if existing.resetAt.IsZero() && existing.count > 0 {
+			existing.resetAt = now.Add(existing.duration)
+		}
+
+		updated = append(updated, existing)
+	}
+
+	bucket.windows = updated
+	bucket.notify()
+	return nil
+}
+
+func (b *MemoryBackend) BlockedUntil(name string) (time.Time, error) {
+	bucket := b.bucket(name)
+
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	return bucket.blockedUntil, nil
+}
+
+func (b *MemoryBackend) Block(name string, until time.Time) error {
+	bucket := b.bucket(name)
+
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	bucket.blockedUntil = until
+	return nil
+}
+
+func (b *MemoryBackend) State(name string) (BucketState, error) {
+	bucket := b.bucket(name)
+
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	state := BucketState{
+		BlockedUntil: bucket.blockedUntil,
+	}
+
+	now := b.clock.Now()
+	for _, w := range bucket.windows {
+		w.expire(now)
+
+		state.Windows = append(state.Windows, Window{
+			Limit:    w.limit,
+			Count:    w.count,
+			Duration: w.duration,
+			ResetAt:  w.resetAt,
+		})
+	}
+
+	return state, nil
+}
+
+func (b *MemoryBackend) Snapshot() (Snapshot, error) {
+	b.mutex.Lock()
+	buckets := make(map[string]*memoryBucket, len(b.buckets))
+	for name, bucket := range b.buckets {
+		buckets[name] = bucket
+	}
+	b.mutex.Unlock()
+
+	snapshot := Snapshot{
+		SavedAt: b.clock.Now(),
+		Buckets: make(map[string]BucketSnapshot, len(buckets)),
+	}
+
+	for name, bucket := range buckets {
+		bucket.mutex.Lock()
+
+		bucketSnapshot := BucketSnapshot{
+			BlockedUntil: bucket.blockedUntil,
+		}
+
+		for _, w := range bucket.windows {
+			w.expire(snapshot.SavedAt)
+
+			bucketSnapshot.Windows = append(bucketSnapshot.Windows, WindowSnapshot{
+				Limit:    w.limit,
+				Count:    w.count,
+				Duration: w.duration,
+				ResetAt:  w.resetAt,
+			})
+		}
+
+		bucket.mutex.Unlock()
+
+		snapshot.Buckets[name] = bucketSnapshot
+	}
+
+	return snapshot, nil
+}
+
+// Restore replaces the state of the buckets in the snapshot. The counts of the
+// windows that have reset since the snapshot was taken are discarded.
+func (b *MemoryBackend) Restore(snapshot Snapshot) error {
+	now := b.clock.Now()
+
+	for name, bucketSnapshot := range snapshot.Buckets {
+		bucket := b.bucket(name)
+
+		bucket.mutex.Lock()
+
+		bucket.blockedUntil = bucketSnapshot.BlockedUntil
+		bucket.windows = bucket.windows[:0]
+
+		for _, ws := range bucketSnapshot.Windows {
+			w := &window{
+				limit:    ws.Limit,
+				duration: ws.Duration,
+				count:    ws.Count,
+				resetAt:  ws.ResetAt,
+			}
+
+			if w.limit < 1 {
+				w.limit = 1
+			}
+
+			w.expire(now)
+			bucket.windows = append(bucket.windows, w)
+		}
+
+		bucket.notify()
+		bucket.mutex.Unlock()
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package ratelimiter
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Windows large enough that the benchmarks never wait on them.
+var (
+	benchmarkWindows = []Window{
+		{Limit: 1 << 30, Duration: 10 * time.Second},
+		{Limit: 1 << 30, Duration: 600 * time.Second},
+	}
+	benchmarkUpdate = []Window{
+		{Limit: 1 << 30, Count: 1, Duration: 10 * time.Second},
+		{Limit: 1 << 30, Count: 1, Duration: 600 * time.Second},
+	}
+)
+
+// reportMemory reports the goroutines and heap in use at the end of a benchmark, since slots
+// that are held by goroutines or timers show up there rather than in allocs/op.
+func reportMemory(b *testing.B) {
+	b.StopTimer()
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	b.ReportMetric(float64(runtime.NumGoroutine()), "goroutines")
+	b.ReportMetric(float64(stats.HeapInuse)/(1<<20), "heap-MB")
+}
+
+func BenchmarkMemoryBackendObtain(b *testing.B) {
+	backend := NewMemoryBackend()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := backend.Obtain(ctx, "NA1", benchmarkWindows); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+
+	reportMemory(b)
+}
+
+func BenchmarkMemoryBackendObtainUpdate(b *testing.B) {
+	backend := NewMemoryBackend()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := backend.Obtain(ctx, "NA1", benchmarkWindows); err != nil {
+				b.Error(err)
+				return
+			}
+
+			if err := backend.Update("NA1", benchmarkUpdate); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+
+	reportMemory(b)
+}
+
+// goroutineBackend is the structure MemoryBackend had before it used fixed windows, kept as the
+// baseline of its benchmarks: each window is a counting semaphore, and every Update starts a
+// goroutine per window that gives the slot back once the window has passed. The goroutines also
+// exit when done is closed, so that they do not outlive the benchmark.
+type goroutineBackend struct {
+	mutex    sync.Mutex
+	limiters map[string][]*semaphore
+	done     chan struct{}
+}
+
+type semaphore struct {
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	capacity int
+	current  int
+}
+
+func (b *goroutineBackend) semaphores(bucket string, initial []Window) []*semaphore {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.limiters[bucket]; !ok {
+		for _, window := range initial {
+			s := &semaphore{capacity: window.Limit}
+			s.cond = sync.NewCond(&s.mutex)
+			b.limiters[bucket] = append(b.limiters[bucket], s)
+		}
+	}
+
+	return b.limiters[bucket]
+}
+
+func (b *goroutineBackend) Obtain(bucket string, initial []Window) {
+	for _, s := range b.semaphores(bucket, initial) {
+		s.mutex.Lock()
+		for s.current >= s.capacity {
+			s.cond.Wait()
+		}
+
+		s.current++
+		s.mutex.Unlock()
+	}
+}
+
+func (b *goroutineBackend) Update(bucket string, windows []Window) {
+	for i, s := range b.semaphores(bucket, nil) {
+		go func(s *semaphore, duration time.Duration) {
+			timer := time.NewTimer(duration)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+			case <-b.done:
+			}
+
+			s.mutex.Lock()
+			s.current--
+			s.cond.Signal()
+			s.mutex.Unlock()
+		}(s, windows[i].Duration)
+	}
+}
+
+func BenchmarkGoroutineBackendObtainUpdate(b *testing.B) {
+	backend := &goroutineBackend{limiters: make(map[string][]*semaphore), done: make(chan struct{})}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			backend.Obtain("NA1", benchmarkWindows)
+			backend.Update("NA1", benchmarkUpdate)
+		}
+	})
+
+	reportMemory(b)
+	close(backend.done)
+}
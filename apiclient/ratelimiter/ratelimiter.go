@@ -3,11 +3,17 @@ package ratelimiter
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter/clock"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/telemetry"
 )
 
 type ConserveUsage struct {
@@ -17,11 +23,57 @@ type ConserveUsage struct {
 }
 
 type RateLimiter struct {
-	Requests      chan *APIRequest
-	httpClient    *http.Client
-	apiKey        string
-	maxRetries    int
-	conserveUsage ConserveUsage
+	Requests          chan *APIRequest
+	httpClient        *http.Client
+	backend           Backend
+	apiKey            string
+	keys              []*APIKey
+	nextKey           uint32
+	userAgent         string
+	maxRetries        int
+	conserveUsage     ConserveUsage
+	starvationTimeout time.Duration
+	schedulers        map[string]*scheduler
+	schedulersMutex   sync.Mutex
+	bucketCounters    map[string]*bucketCounters
+	countersMutex     sync.Mutex
+
+	// waiting counts the requests that are pending but not queued or in flight in a bucket,
+	// by key name. It is guarded by countersMutex.
+	waiting map[string]int
+	clock             clock.Clock
+	observer          Observer
+	events            chan Event
+	eventsDelivered   chan struct{}
+	droppedEvents     int64
+	limits            limitsTracker
+	metrics           telemetry.Metrics
+	tracer            telemetry.Tracer
+	stateStore        StateStore
+	saveInterval      time.Duration
+	maxQueueDepth     int
+	breakerOptions    *BreakerOptions
+	breakers          map[string]*breaker
+	breakersMutex     sync.Mutex
+	retryPolicy       RetryPolicy
+	queueDepth        int64
+
+	// pending counts the submitted requests that have not received a response.
+	pending    sync.WaitGroup
+	closed     bool
+	closeMutex sync.RWMutex
+
+	// stateRestored is set once Start has restored the state from the state store, and saved is
+	// closed when saveStatePeriodically returns. Both are guarded by closeMutex.
+	stateRestored bool
+	saved         chan struct{}
+
+	// aborted is canceled by abort when Close gives up waiting for the pending requests.
+	aborted context.Context
+	abort   context.CancelFunc
+
+	// done is closed when the rate limiter is closed, which stops Start.
+	done chan struct{}
 }
 
 func NewRateLimiter(requests chan *APIRequest, apiKey string) *RateLimiter {
@@ -29,16 +81,28 @@ func NewRateLimiter(requests chan *APIRequest, apiKey string) *RateLimiter {
 		panic("requests channel cannot be nil")
 	}
 
+	aborted, abort := context.WithCancel(context.Background())
+
 	return &RateLimiter{
-		Requests:   requests,
-		httpClient: &http.Client{},
-		apiKey:     apiKey,
-		maxRetries: -1,
+		Requests:       requests,
+		aborted:        aborted,
+		abort:          abort,
+		done:           make(chan struct{}),
+		httpClient:     &http.Client{},
+		backend:        NewMemoryBackend(),
+		clock:          clock.Real,
+		schedulers:     make(map[string]*scheduler),
+		bucketCounters: make(map[string]*bucketCounters),
+		waiting:        make(map[string]int),
+		breakers:       make(map[string]*breaker),
+		apiKey:         apiKey,
+		maxRetries:     -1,
 		conserveUsage: ConserveUsage{
 			RegionPercent: 0,
 			MethodPercent: 0,
 			IgnoreLimits:  []MethodID{},
 		},
+		retryPolicy: DefaultRetryPolicy,
 	}
 }
 
@@ -56,8 +120,182 @@ func (rl *RateLimiter) SetUsageConservation(conserveUsage ConserveUsage) {
 	rl.conserveUsage = conserveUsage
 }
 
+// SetAPIKey sets the API key that requests are sent with, replacing the key pool set with
+// SetAPIKeys, if any.
 func (rl *RateLimiter) SetAPIKey(apiKey string) {
 	rl.apiKey = apiKey
+	rl.keys = nil
+}
+
+// SetHTTPClient sets the HTTP client used to send requests.
+// If httpClient is nil, a default client is used.
+func (rl *RateLimiter) SetHTTPClient(httpClient *http.Client) {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	rl.httpClient = httpClient
+}
+
+// SetBackend sets the Backend that stores the rate limit state.
+// It must be called before Start. If backend is nil, a MemoryBackend is used.
+func (rl *RateLimiter) SetBackend(backend Backend) {
+	if backend == nil {
+		backend = NewMemoryBackend()
+	}
+
+	rl.backend = backend
+}
+
+// SetStarvationTimeout sets how long a request can wait on a rate limit before it is
+// served ahead of requests with a higher priority. It must be called before Start.
+// If starvationTimeout is 0, requests are always served in order of priority.
+func (rl *RateLimiter) SetStarvationTimeout(starvationTimeout time.Duration) {
+	rl.starvationTimeout = starvationTimeout
+}
+
+// SetClock sets the clock used to time waits, blocks and circuit breakers, and the clock of the
+// Backend if it has a SetClock method, like MemoryBackend and RedisBackend. It must be called after SetBackend
+// and before Start. Tests can use a clock.Fake to control the passage of time.
+func (rl *RateLimiter) SetClock(c clock.Clock) {
+	if c == nil {
+		c = clock.Real
+	}
+
+	rl.clock = c
+
+	if backend, ok := rl.backend.(interface{ SetClock(clock.Clock) }); ok {
+		backend.SetClock(c)
+	}
+}
+
+// SetMetrics sets the Metrics that record request latencies, queue wait times and 429 responses.
+func (rl *RateLimiter) SetMetrics(metrics telemetry.Metrics) {
+	rl.metrics = metrics
+}
+
+// SetTracer sets the Tracer used to trace the time requests spend waiting on rate limits.
+func (rl *RateLimiter) SetTracer(tracer telemetry.Tracer) {
+	rl.tracer = tracer
+}
+
+// SetStateStore sets the store that the rate limit state is restored from when the rate
+// limiter starts, and saved to every saveInterval and when the rate limiter is closed. If
+// saveInterval is 0, the state is only saved on Close. It must be called before Start.
+// The state is only saved and restored if the Backend implements Snapshotter.
+//
+// If the state cannot be restored or saved, the error is sent to the observer as an
+// EventStateStoreError event.
+func (rl *RateLimiter) SetStateStore(store StateStore, saveInterval time.Duration) {
+	rl.stateStore = store
+	rl.saveInterval = saveInterval
+}
+
+// startStateStore restores the state from the state store, if one is set, and starts saving it
+// periodically. It is called by Start rather than SetStateStore, so that the state is restored
+// into the Backend that is used, whichever was set first.
+func (rl *RateLimiter) startStateStore() {
+	rl.closeMutex.Lock()
+	if rl.closed || rl.stateStore == nil {
+		rl.closeMutex.Unlock()
+		return
+	}
+
+	err := rl.restoreState()
+	rl.stateRestored = true
+
+	if rl.saveInterval > 0 {
+		rl.saved = make(chan struct{})
+		go rl.saveStatePeriodically(rl.saved)
+	}
+
+	rl.closeMutex.Unlock()
+
+	if err != nil {
+		rl.notify(Event{Type: EventStateStoreError, Time: rl.clock.Now(), Err: err})
+	}
+}
+
+// restoreState restores the backend's state from the state store.
+func (rl *RateLimiter) restoreState() error {
+	if rl.stateStore == nil {
+		return nil
+	}
+
+	snapshotter, ok := rl.backend.(Snapshotter)
+	if !ok {
+		return nil
+	}
+
+	snapshot, err := rl.stateStore.Load()
+	if err != nil || snapshot == nil {
+		return err
+	}
+
+	return snapshotter.Restore(*snapshot)
+}
+
+// saveState saves a snapshot of the backend's state to the state store.
+func (rl *RateLimiter) saveState() error {
+	if rl.stateStore == nil {
+		return nil
+	}
+
+	snapshotter, ok := rl.backend.(Snapshotter)
+	if !ok {
+		return nil
+	}
+
+	snapshot, err := snapshotter.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	return rl.stateStore.Save(snapshot)
+}
+
+// saveStatePeriodically saves the state every saveInterval until the rate limiter is closed,
+// then closes saved.
+func (rl *RateLimiter) saveStatePeriodically(saved chan<- struct{}) {
+	defer close(saved)
+
+	for {
+		timer := rl.clock.NewTimer(rl.saveInterval)
+
+		select {
+		case <-rl.done:
+			timer.Stop()
+			return
+		case <-timer.C():
+			// Failures are retried on the next tick, and the state is saved again on Close
+			if err := rl.saveState(); err != nil {
+				rl.notify(Event{Type: EventStateStoreError, Time: rl.clock.Now(), Err: err})
+			}
+		}
+	}
+}
+
+// SetMaxQueueDepth sets the maximum number of submitted requests that can wait for a
+// rate limit slot at once. Submit returns ErrQueueFull for requests beyond it.
+// If maxQueueDepth is 0 or less, the queue is unbounded.
+func (rl *RateLimiter) SetMaxQueueDepth(maxQueueDepth int) {
+	rl.maxQueueDepth = maxQueueDepth
+}
+
+// SetUserAgent sets the User-Agent header sent with every request.
+// If userAgent is empty, the HTTP client's default User-Agent is used.
+func (rl *RateLimiter) SetUserAgent(userAgent string) {
+	rl.userAgent = userAgent
+}
+
+// SetRetryPolicy sets the policy that decides whether failed requests are retried.
+// If retryPolicy is nil, DefaultRetryPolicy is used.
+func (rl *RateLimiter) SetRetryPolicy(retryPolicy RetryPolicy) {
+	if retryPolicy == nil {
+		retryPolicy = DefaultRetryPolicy
+	}
+
+	rl.retryPolicy = retryPolicy
 }
 
 // SetMaxRetries sets the maximum number of retries for a request.
@@ -76,14 +314,59 @@ type APIRequest struct {
 	Region   string
 	MethodID MethodID
 	URL      string
-	Response chan<- *http.Response
+	Priority Priority
+	Key      string // The name of the key in the key pool to send the request with, if it is pinned to one
+	Response chan<- *APIResponse
 	Retries  int
+
+	// RetryPolicy overrides the rate limiter's retry policy for this request, if set.
+	RetryPolicy RetryPolicy
+
+	// NonBlocking makes the request fail with ErrWouldBlock instead of waiting on a rate limit.
+	NonBlocking bool
+
+	// Header holds headers sent with the request in addition to the API key.
+	Header http.Header
+
+	// KeyName is the name of the key in the key pool that the request was last sent with.
+	// QueueWait is the total time it waited for rate limit slots, and Latency is how long its
+	// last HTTP request took to receive a response. They are set by the rate limiter.
+	KeyName   string
+	QueueWait time.Duration
+	Latency   time.Duration
+
+	// submitted is set if the request was queued with Submit, so that Close waits for it.
+	submitted bool
+
+	// queued is set while a submitted request counts towards the queue depth.
+	queued bool
+
+	// waiting is set while the request is counted in RateLimiter.waiting under waitingKey.
+	waiting    bool
+	waitingKey string
+}
+
+// APIResponse is the result of an APIRequest. Err is set if the request failed without a response.
+type APIResponse struct {
+	Response *http.Response
+	Err      error
+}
+
+// ErrClosed is returned for requests made after the rate limiter was closed, and for
+// requests that were still pending when Close gave up waiting for them.
+var ErrClosed = errors.New("ratelimiter: rate limiter is closed")
+
+// ErrQueueFull is returned by Submit when the maximum queue depth has been reached.
+var ErrQueueFull = errors.New("ratelimiter: queue is full")
+
+// ErrWouldBlock is returned for non-blocking requests that would have to wait on a rate limit.
+// Wait is the estimated time until the request could be made.
+type ErrWouldBlock struct {
+	Wait time.Duration
 }
 
-type RateLimit struct {
-	shortLimiter *limiterMu
-	longLimiter  *limiterMu
-	blockedUntil time.Time
+func (e ErrWouldBlock) Error() string {
+	return fmt.Sprintf("ratelimiter: request would wait %s on a rate limit", e.Wait)
 }
 
 const (
@@ -91,197 +374,702 @@ const (
 	initialMethodLimit = 5
 )
 
+// The windows used for a bucket until Riot reports its real limits.
+var (
+	initialRegionWindows = []Window{
+		{Limit: initialRegionLimit, Duration: time.Second},
+		{Limit: initialRegionLimit, Duration: 2 * time.Minute},
+	}
+	initialMethodWindows = []Window{
+		{Limit: initialMethodLimit, Duration: 10 * time.Second},
+	}
+)
+
+// regionBucket returns the name of the bucket of a region's application rate limits.
+// The buckets of the keys in a key pool are prefixed with the key's name.
+func regionBucket(keyName, region string) string {
+	if keyName == "" {
+		return region
+	}
+
+	return keyName + "/" + region
+}
+
+func methodBucket(keyName, region string, methodID MethodID) string {
+	return regionBucket(keyName, region) + ":" + methodID.String()
+}
+
+// scheduler returns the scheduler of the given bucket, creating it if needed.
+func (rl *RateLimiter) scheduler(bucket string) *scheduler {
+	rl.schedulersMutex.Lock()
+	defer rl.schedulersMutex.Unlock()
+
+	s, ok := rl.schedulers[bucket]
+	if !ok {
+		s = &scheduler{
+			starvationTimeout: rl.starvationTimeout,
+			clock:             rl.clock,
+		}
+
+		rl.schedulers[bucket] = s
+	}
+
+	return s
+}
+
+// obtain waits for the request's turn in the bucket, then obtains a slot from the backend.
+// It reports whether the request had to wait.
+//
+// Non-blocking requests do not wait for their turn, since the request holding it may be
+// waiting for the bucket to have room. They try to obtain a slot straight away, and return
+// ErrWouldBlock only if the bucket is full.
+func (rl *RateLimiter) obtain(ctx context.Context, req *APIRequest, bucket string, initial []Window) (waited bool, err error) {
+	if req.NonBlocking {
+		wait, err := rl.backend.TryObtain(bucket, initial)
+		if err == nil && wait > 0 {
+			err = ErrWouldBlock{Wait: wait}
+		}
+
+		return false, err
+	}
+
+	s := rl.scheduler(bucket)
+	if !s.tryAcquire() {
+		if err := s.acquire(ctx, req.Priority); err != nil {
+			return true, err
+		}
+
+		waited = true
+	}
+
+	defer s.release()
+
+	wait, err := rl.backend.TryObtain(bucket, initial)
+	if err != nil || wait <= 0 {
+		return waited, err
+	}
+
+	return true, rl.backend.Obtain(ctx, bucket, initial)
+}
+
+// Submit queues a request. The result is sent to the request's Response channel.
+// It returns ErrClosed if the rate limiter has been closed, ErrQueueFull if the maximum
+// queue depth has been reached, or the error of the request's Context if it is done
+// before the request could be queued.
+func (rl *RateLimiter) Submit(req *APIRequest) error {
+	rl.closeMutex.RLock()
+	if rl.closed {
+		rl.closeMutex.RUnlock()
+		return ErrClosed
+	}
+
+	if depth := atomic.AddInt64(&rl.queueDepth, 1); rl.maxQueueDepth > 0 && depth > int64(rl.maxQueueDepth) {
+		atomic.AddInt64(&rl.queueDepth, -1)
+		rl.closeMutex.RUnlock()
+		return ErrQueueFull
+	}
+
+	req.queued = true
+	req.submitted = true
+	rl.pending.Add(1)
+	rl.closeMutex.RUnlock()
+
+	// The request is pending while it is blocked here, before its key is selected
+	rl.startWaiting(req, req.Key)
+
+	var done <-chan struct{}
+	if req.Context != nil {
+		done = req.Context.Done()
+	}
+
+	select {
+	case rl.Requests <- req:
+		return nil
+	case <-done:
+		rl.dequeue(req)
+		rl.stopWaiting(req)
+		rl.pending.Done()
+		return req.Context.Err()
+	}
+}
+
+// startWaiting counts the request as pending under keyName until it is queued in a bucket
+// or responded to, so that Pending counts it while it is not in a bucket.
+func (rl *RateLimiter) startWaiting(req *APIRequest, keyName string) {
+	rl.countersMutex.Lock()
+	defer rl.countersMutex.Unlock()
+
+	req.waiting = true
+	req.waitingKey = keyName
+	rl.waiting[keyName]++
+}
+
+// stopWaiting stops counting the request as pending outside of a bucket, if it is.
+func (rl *RateLimiter) stopWaiting(req *APIRequest) {
+	rl.countersMutex.Lock()
+	defer rl.countersMutex.Unlock()
+
+	if req.waiting {
+		req.waiting = false
+		rl.waiting[req.waitingKey]--
+	}
+}
+
+// dequeue removes a submitted request from the queue depth once it stops waiting.
+func (rl *RateLimiter) dequeue(req *APIRequest) {
+	if req.queued {
+		req.queued = false
+		atomic.AddInt64(&rl.queueDepth, -1)
+	}
+}
+
+// Close stops accepting new requests and waits for the pending requests to finish.
+// If ctx is done before they finish, the pending requests fail with ErrClosed and
+// ctx.Err() is returned. Close then waits for the events to be delivered to an asynchronous
+// Observer, saves the state to the StateStore, if one is set, and closes the Backend if it
+// implements io.Closer.
+func (rl *RateLimiter) Close(ctx context.Context) error {
+	rl.closeMutex.Lock()
+	if rl.closed {
+		rl.closeMutex.Unlock()
+		return nil
+	}
+
+	rl.closed = true
+	rl.closeMutex.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		rl.pending.Wait()
+		close(finished)
+	}()
+
+	var err error
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		err = ctx.Err()
+
+		// Abort the pending requests and wait for them to return
+		rl.abort()
+		<-finished
+	}
+
+	close(rl.done)
+
+	rl.closeMutex.RLock()
+	stateRestored, saved := rl.stateRestored, rl.saved
+	rl.closeMutex.RUnlock()
+
+	// Wait for a periodic save in progress, so that it cannot overwrite the final one
+	if saved != nil {
+		<-saved
+	}
+
+	if rl.eventsDelivered != nil {
+		<-rl.eventsDelivered
+	}
+
+	// The state is not saved if it was never restored, so that the saved state is not replaced with an empty one
+	if stateRestored {
+		if saveErr := rl.saveState(); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+
+	if closer, ok := rl.backend.(io.Closer); ok {
+		if closeErr := closer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// respond sends the result of a request to its Response channel.
+func (rl *RateLimiter) respond(req *APIRequest, res *APIResponse) {
+	rl.dequeue(req)
+	rl.stopWaiting(req)
+	req.Response <- res
+
+	if req.submitted {
+		rl.pending.Done()
+	}
+}
+
+// requestContext returns a context for handling the request that is also canceled
+// when the rate limiter aborts its pending requests.
+func (rl *RateLimiter) requestContext(req *APIRequest) (context.Context, context.CancelFunc) {
+	parent := req.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+
+	go func() {
+		select {
+		case <-rl.aborted.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// cancelOnClose is a response body that cancels the context of its request when it is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel    context.CancelFunc
+	discarded bool
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// discardBody closes the body of a response that is not returned, without canceling the
+// context of its request, which is still used to wait before retrying it.
+func discardBody(resp *http.Response) {
+	if body, ok := resp.Body.(*cancelOnClose); ok {
+		body.ReadCloser.Close()
+		body.discarded = true
+		return
+	}
+
+	resp.Body.Close()
+}
+
+// wrapError returns ErrClosed if the error was caused by Close aborting the request.
+func (rl *RateLimiter) wrapError(err error) error {
+	if rl.aborted.Err() != nil {
+		return ErrClosed
+	}
+
+	return err
+}
+
+// waitUntil waits until the time a bucket is blocked until. Non-blocking requests
+// return ErrWouldBlock instead.
+func (rl *RateLimiter) waitUntil(ctx context.Context, req *APIRequest, until time.Time) error {
+	if req.NonBlocking {
+		return ErrWouldBlock{Wait: clock.Until(rl.clock, until)}
+	}
+
+	return rl.sleep(ctx, clock.Until(rl.clock, until))
+}
+
+// sleep waits for the duration, returning early with an error if ctx is done.
+func (rl *RateLimiter) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := rl.clock.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
 func (rl *RateLimiter) Start() {
-	regionLimiters := make(map[string]*RateLimit)
-	methodLimiters := make(map[string]*RateLimit)
-
-	regionMutex := sync.RWMutex{}
-	methodMutex := sync.RWMutex{}
-
-	for req := range rl.Requests {
-		go func(req *APIRequest) {
-			var regionLimiter *RateLimit
-			var methodLimiter *RateLimit
-
-			var wg sync.WaitGroup
-			wg.Add(2)
-			go func() {
-				defer wg.Done()
-
-				var ok bool
-				regionMutex.RLock()
-				regionLimiter, ok = regionLimiters[req.Region]
-				regionMutex.RUnlock()
-
-				if !ok {
-					regionMutex.Lock()
-					regionLimiters[req.Region] = &RateLimit{
-						shortLimiter: newLimiterMu(initialRegionLimit),
-						longLimiter:  newLimiterMu(initialRegionLimit),
-						blockedUntil: time.Time{},
-					}
-					regionLimiter = regionLimiters[req.Region]
-					regionMutex.Unlock()
-				}
-			}()
-
-			go func() {
-				defer wg.Done()
-
-				var ok bool
-				methodMutex.RLock()
-				methodLimiter, ok = methodLimiters[req.Region+req.MethodID.String()]
-				methodMutex.RUnlock()
-
-				if !ok {
-					methodMutex.Lock()
-					methodLimiters[req.Region+req.MethodID.String()] = &RateLimit{
-						shortLimiter: newLimiterMu(initialMethodLimit),
-						longLimiter:  newLimiterMu(initialMethodLimit),
-						blockedUntil: time.Time{},
-					}
-					methodLimiter = methodLimiters[req.Region+req.MethodID.String()]
-					methodMutex.Unlock()
-				}
-			}()
+	rl.startStateStore()
 
-			wg.Wait()
+	for {
+		select {
+		case <-rl.done:
+			return
+		case req := <-rl.Requests:
+			go rl.handle(req)
+		}
+	}
+}
 
-			// Check if the region is blocked
-			if time.Now().Before(regionLimiter.blockedUntil) {
-				time.Sleep(time.Until(regionLimiter.blockedUntil))
-			}
+func (rl *RateLimiter) handle(req *APIRequest) {
+	ctx, cancel := rl.requestContext(req)
 
-			// Check if the method is blocked
-			if time.Now().Before(methodLimiter.blockedUntil) {
-				time.Sleep(time.Until(methodLimiter.blockedUntil))
-			}
+	// Once a response is received, its body is read after handle returns, so the context is
+	// canceled when the body is closed instead, unless the response is discarded
+	var body *cancelOnClose
+	defer func() {
+		if body == nil || body.discarded {
+			cancel()
+		}
+	}()
 
-			// Obtain a lock on the region and method limiters
-			// Add the request to the limiter channels
-			regionLimiter.shortLimiter.Obtain()
-			regionLimiter.longLimiter.Obtain()
-			methodLimiter.shortLimiter.Obtain()
-
-			// Create a new HTTP request
-			var (
-				httpRequest *http.Request
-				err         error
-			)
-
-			if req.Context == nil {
-				httpRequest, err = http.NewRequest("GET", req.URL, nil)
-			} else {
-				httpRequest, err = http.NewRequestWithContext(req.Context, "GET", req.URL, nil)
-			}
+	key, err := rl.selectKey(req)
+	if err != nil {
+		rl.respond(req, &APIResponse{Err: err})
+		return
+	}
 
-			if err != nil {
-				req.Response <- &http.Response{
-					StatusCode: http.StatusInternalServerError,
-				}
+	// Fail fast if the region is failing, rather than waiting on its rate limits
+	circuit := rl.breaker(req.Region)
+	var probe, recorded bool
+	if circuit != nil {
+		if probe, err = circuit.allow(); err != nil {
+			rl.respond(req, &APIResponse{Err: err})
+			return
+		}
 
-				// Remove the request from the limiter channels
-				regionLimiter.shortLimiter.Release()
-				regionLimiter.longLimiter.Release()
-				methodLimiter.shortLimiter.Release()
-				return
+		defer func() {
+			if !recorded {
+				circuit.abandon(probe)
 			}
+		}()
+	}
 
-			// Set the API key as a header
-			httpRequest.Header.Set("X-Riot-Token", rl.apiKey)
-
-			// Send the HTTP request
-			resp, err := (*rl.httpClient).Do(httpRequest)
-			if err == nil && resp.StatusCode == http.StatusOK {
-				rl.updateRateLimits(resp, req.MethodID, regionLimiter, methodLimiter)
-				req.Response <- resp
-			} else if err == nil && resp.StatusCode == http.StatusForbidden {
-				req.Response <- resp
-			} else if err == nil && resp.StatusCode == http.StatusTooManyRequests {
-				// Retry the request if Retries is less than maxRetries, or if maxRetries is -1. Otherwise, send the response to the channel
-				if req.Retries < rl.maxRetries || rl.maxRetries == -1 {
-					handleRateLimitedResponse(resp, regionLimiter, methodLimiter)
-					req.Retries++
-					rl.Requests <- req
-				} else {
-					req.Response <- resp
-					handleRateLimitedResponse(resp, regionLimiter, methodLimiter)
-				}
-			} else if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
-				req.Response <- &http.Response{
-					StatusCode: http.StatusRequestTimeout,
-				}
+	req.KeyName = key.Name
+	regionBucket := regionBucket(key.Name, req.Region)
+	methodBucket := methodBucket(key.Name, req.Region, req.MethodID)
 
-				// Remove the request from the limiter channels
-				regionLimiter.shortLimiter.Release()
-				regionLimiter.longLimiter.Release()
-				methodLimiter.shortLimiter.Release()
-			} else {
-				if !isBadRequest(resp) && (req.Retries < rl.maxRetries || rl.maxRetries == -1) {
-					time.Sleep(15 * time.Second)
-
-					// Remove the request from the limiter channels
-					regionLimiter.shortLimiter.Release()
-					regionLimiter.longLimiter.Release()
-					methodLimiter.shortLimiter.Release()
-
-					req.Retries++
-					rl.Requests <- req
-				} else {
-					req.Response <- resp
-
-					time.Sleep(15 * time.Second)
-
-					// Remove the request from the limiter channels
-					regionLimiter.shortLimiter.Release()
-					regionLimiter.longLimiter.Release()
-					methodLimiter.shortLimiter.Release()
-				}
-			}
-		}(req)
+	regionCounters, methodCounters := rl.counters(key.Name, req.Region, req.MethodID)
+	addCount(1, &regionCounters.queued, &methodCounters.queued)
+	rl.stopWaiting(req)
+
+	queuedAt := rl.clock.Now()
+	var waitSpan telemetry.Span
+	if rl.tracer != nil && req.Context != nil {
+		_, waitSpan = rl.tracer.Start(req.Context, telemetry.SpanWait,
+			telemetry.Attribute{Key: telemetry.AttrRegion, Value: req.Region},
+			telemetry.Attribute{Key: telemetry.AttrMethodID, Value: req.MethodID.String()},
+		)
+	}
+
+	var throttled bool
+
+	// Check if the region is blocked
+	if blockedUntil, err := rl.backend.BlockedUntil(regionBucket); err == nil && rl.clock.Now().Before(blockedUntil) {
+		throttled = true
+
+		if err := rl.waitUntil(ctx, req, blockedUntil); err != nil {
+			addCount(-1, &regionCounters.queued, &methodCounters.queued)
+			endSpan(waitSpan, err)
+			rl.respond(req, &APIResponse{Err: rl.wrapError(err)})
+			return
+		}
+	}
+
+	// Check if the method is blocked
+	if blockedUntil, err := rl.backend.BlockedUntil(methodBucket); err == nil && rl.clock.Now().Before(blockedUntil) {
+		throttled = true
+
+		if err := rl.waitUntil(ctx, req, blockedUntil); err != nil {
+			addCount(-1, &regionCounters.queued, &methodCounters.queued)
+			endSpan(waitSpan, err)
+			rl.respond(req, &APIResponse{Err: rl.wrapError(err)})
+			return
+		}
+	}
+
+	// Obtain a slot in the region and method buckets
+	waited, err := rl.obtain(ctx, req, regionBucket, initialRegionWindows)
+	if err != nil {
+		addCount(-1, &regionCounters.queued, &methodCounters.queued)
+		endSpan(waitSpan, err)
+		rl.respond(req, &APIResponse{Err: rl.wrapError(err)})
+		return
+	}
+
+	throttled = throttled || waited
+
+	waited, err = rl.obtain(ctx, req, methodBucket, initialMethodWindows)
+	if err != nil {
+		addCount(-1, &regionCounters.queued, &methodCounters.queued)
+		endSpan(waitSpan, err)
+		rl.backend.Release(regionBucket)
+		rl.respond(req, &APIResponse{Err: rl.wrapError(err)})
+		return
+	}
+
+	throttled = throttled || waited
+
+	addCount(-1, &regionCounters.queued, &methodCounters.queued)
+	endSpan(waitSpan, nil)
+	rl.dequeue(req)
+	req.QueueWait += clock.Since(rl.clock, queuedAt)
+
+	if throttled {
+		rl.emit(req, key, Event{Type: EventThrottled, Wait: clock.Since(rl.clock, queuedAt), Retries: req.Retries})
+	}
+
+	if rl.metrics != nil {
+		rl.metrics.ObserveQueueWait(req.Region, req.MethodID.String(), clock.Since(rl.clock, queuedAt))
+	}
+
+	// Create a new HTTP request
+	httpRequest, err := http.NewRequestWithContext(ctx, "GET", req.URL, nil)
+	if err == nil {
+		// The context may have been canceled while the slots were being obtained
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		// Give back the slots, since the request was never sent
+		rl.backend.Release(regionBucket)
+		rl.backend.Release(methodBucket)
+
+		rl.respond(req, &APIResponse{Err: rl.wrapError(err)})
+		return
+	}
+
+	// Set the API key as a header
+	httpRequest.Header.Set("X-Riot-Token", key.Key)
+
+	if rl.userAgent != "" {
+		httpRequest.Header.Set("User-Agent", rl.userAgent)
+	}
+
+	for name, values := range req.Header {
+		if http.CanonicalHeaderKey(name) != "X-Riot-Token" {
+			httpRequest.Header[name] = values
+		}
+	}
+
+	// Trace each attempt until its response headers are received, with the span in the
+	// request's context so that traced transports can propagate it
+	var requestSpan telemetry.Span
+	if rl.tracer != nil && req.Context != nil {
+		var spanCtx context.Context
+		spanCtx, requestSpan = rl.tracer.Start(ctx, telemetry.SpanRequest,
+			telemetry.Attribute{Key: telemetry.AttrRegion, Value: req.Region},
+			telemetry.Attribute{Key: telemetry.AttrMethodID, Value: req.MethodID.String()},
+			telemetry.Attribute{Key: telemetry.AttrURL, Value: req.URL},
+			telemetry.Attribute{Key: telemetry.AttrRetries, Value: req.Retries},
+		)
+
+		httpRequest = httpRequest.WithContext(spanCtx)
+	}
+
+	// Send the HTTP request
+	addCount(1, &regionCounters.inFlight, &methodCounters.inFlight)
+	sentAt := rl.clock.Now()
+	resp, err := rl.httpClient.Do(httpRequest)
+	req.Latency = clock.Since(rl.clock, sentAt)
+	addCount(-1, &regionCounters.inFlight, &methodCounters.inFlight)
+
+	if requestSpan != nil && err == nil {
+		requestSpan.SetAttributes(telemetry.Attribute{Key: telemetry.AttrStatus, Value: resp.StatusCode})
 	}
+
+	endSpan(requestSpan, err)
+
+	if err == nil {
+		body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+		resp.Body = body
+	}
+
+	if circuit != nil {
+		if err == nil {
+			recorded = true
+			circuit.record(probe, resp.StatusCode >= 500)
+		} else if ctx.Err() == nil || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// Network errors and timeouts are failures, but requests canceled by the caller are not
+			recorded = true
+			circuit.record(probe, true)
+		}
+	}
+
+	if rl.metrics != nil {
+		var statusCode int
+		if err == nil {
+			statusCode = resp.StatusCode
+		}
+
+		rl.metrics.ObserveRequest(req.Region, req.MethodID.String(), statusCode, req.Latency)
+	}
+
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		addCount(1, &regionCounters.rateLimited, &methodCounters.rateLimited)
+
+		if rl.metrics != nil {
+			rl.metrics.IncRateLimited(req.Region, req.MethodID.String(), resp.Header.Get("X-Rate-Limit-Type"))
+		}
+	}
+
+	if err == nil && resp.StatusCode == http.StatusOK {
+		rl.updateRateLimits(resp, req, key, regionBucket, methodBucket)
+		rl.respond(req, &APIResponse{Response: resp})
+	} else if err == nil && resp.StatusCode == http.StatusForbidden {
+		rl.emit(req, key, Event{Type: EventForbidden, StatusCode: resp.StatusCode, Retries: req.Retries})
+		rl.respond(req, &APIResponse{Response: resp})
+	} else if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter := rl.handleRateLimitedResponse(resp, regionBucket, methodBucket)
+		rl.emit(req, key, Event{
+			Type:       EventRateLimited,
+			Wait:       retryAfter,
+			Retries:    req.Retries,
+			StatusCode: resp.StatusCode,
+			LimitType:  resp.Header.Get("X-Rate-Limit-Type"),
+		})
+
+		rl.retry(ctx, req, key, resp, nil, retryAfter, regionCounters, methodCounters)
+	} else if err != nil && ctx.Err() != nil {
+		// The request was canceled, so it must not be retried
+		rl.respond(req, &APIResponse{Err: rl.wrapError(ctx.Err())})
+	} else {
+		rl.retry(ctx, req, key, resp, err, 0, regionCounters, methodCounters)
+	}
+}
+
+// retry asks the retry policy whether the failed request should be retried. If so, it
+// waits for the policy's delay and queues the request again. Otherwise, it responds
+// with the response or error.
+func (rl *RateLimiter) retry(ctx context.Context, req *APIRequest, key *APIKey, resp *http.Response, err error, retryAfter time.Duration, regionCounters, methodCounters *bucketCounters) {
+	policy := req.RetryPolicy
+	if policy == nil {
+		policy = rl.retryPolicy
+	}
+
+	attempt := RetryAttempt{
+		MethodID:   req.MethodID,
+		Retries:    req.Retries,
+		Err:        err,
+		RetryAfter: retryAfter,
+	}
+
+	if resp != nil {
+		attempt.StatusCode = resp.StatusCode
+	}
+
+	retry, delay := policy.Retry(attempt)
+
+	// Retry the request if Retries is less than maxRetries, or if maxRetries is -1. Otherwise, send the response to the channel
+	if !retry || (req.Retries >= rl.maxRetries && rl.maxRetries != -1) {
+		rl.respond(req, &APIResponse{Response: resp, Err: err})
+		return
+	}
+
+	if resp != nil {
+		discardBody(resp)
+	}
+
+	if req.NonBlocking && attempt.StatusCode == http.StatusTooManyRequests {
+		rl.respond(req, &APIResponse{Err: ErrWouldBlock{Wait: delay}})
+		return
+	}
+
+	rl.emit(req, key, Event{Type: EventRetry, Wait: delay, Retries: req.Retries + 1, StatusCode: attempt.StatusCode, Err: err})
+
+	// The request is still pending while it waits to be retried and queued again
+	rl.startWaiting(req, key.Name)
+
+	if err := rl.sleep(ctx, delay); err != nil {
+		rl.respond(req, &APIResponse{Err: rl.wrapError(err)})
+		return
+	}
+
+	req.Retries++
+	addCount(1, &regionCounters.retries, &methodCounters.retries)
+	rl.Requests <- req
 }
 
-func (rl *RateLimiter) updateRateLimits(resp *http.Response, methodID MethodID, regionLimiter *RateLimit, methodLimiter *RateLimit) {
+func (rl *RateLimiter) updateRateLimits(resp *http.Response, req *APIRequest, key *APIKey, regionBucket, methodBucket string) {
 	appRateLimitHeader := resp.Header.Get("X-App-Rate-Limit")
 	appRateLimitCountHeader := resp.Header.Get("X-App-Rate-Limit-Count")
 	methodRateLimitHeader := resp.Header.Get("X-Method-Rate-Limit")
 	methodRateLimitCountHeader := resp.Header.Get("X-Method-Rate-Limit-Count")
 
 	if appRateLimitHeader != "" && appRateLimitCountHeader != "" {
-		shortLimitInfo, longLimitInfo := getShortAndLongLimits(appRateLimitHeader)
-		shortCountInfo, longCountInfo := getShortAndLongLimits(appRateLimitCountHeader)
-
-		rl.updateRateLimit(methodID, shortLimitInfo, shortCountInfo, regionLimiter.shortLimiter, &regionLimiter.blockedUntil, rl.conserveUsage.RegionPercent, true)
-		rl.updateRateLimit(methodID, longLimitInfo, longCountInfo, regionLimiter.longLimiter, &regionLimiter.blockedUntil, rl.conserveUsage.RegionPercent, true)
-	} else {
-		// Remove the request from the limiter channels
-		go func() {
-			time.Sleep(15 * time.Second)
-			regionLimiter.shortLimiter.Release()
-			regionLimiter.longLimiter.Release()
-		}()
+		windows := rl.updateWindows(req.MethodID, appRateLimitHeader, appRateLimitCountHeader, regionBucket, rl.conserveUsage.RegionPercent, true)
+		if windows != nil && rl.limits.changed(regionBucket, windows) {
+			rl.emit(req, key, Event{Type: EventLimitsUpdated, LimitType: "application", Windows: windows})
+		}
 	}
 
 	if methodRateLimitHeader != "" && methodRateLimitCountHeader != "" {
-		rl.updateRateLimit(methodID, methodRateLimitHeader, methodRateLimitCountHeader, methodLimiter.shortLimiter, &methodLimiter.blockedUntil, rl.conserveUsage.MethodPercent, false)
-	} else {
-		// Remove the request from the limiter channels
-		go func() {
-			time.Sleep(15 * time.Second)
-			methodLimiter.shortLimiter.Release()
-		}()
+		windows := rl.updateWindows(req.MethodID, methodRateLimitHeader, methodRateLimitCountHeader, methodBucket, rl.conserveUsage.MethodPercent, false)
+		if windows != nil && rl.limits.changed(methodBucket, windows) {
+			rl.emit(req, key, Event{Type: EventLimitsUpdated, LimitType: "method", Windows: windows})
+		}
 	}
 }
 
-func (rl *RateLimiter) updateRateLimit(methodID MethodID, limitInfo, countInfo string, limiterChannel *limiterMu, blockedUntil *time.Time, conservePercent int, isRegionHeader bool) {
-	limitSplit := strings.Split(limitInfo, ":")
-	countSplit := strings.Split(countInfo, ":")
+// updateWindows applies the windows of a pair of limit and count headers to the bucket.
+// The counts are matched to the limits by their window duration. If the limit header
+// has no valid windows, the bucket's windows are left unchanged and nil is returned.
+func (rl *RateLimiter) updateWindows(methodID MethodID, limitHeader, countHeader string, bucket string, conservePercent int, isRegionHeader bool) []Window {
+	limits := ParseRateLimitHeaders(limitHeader, countHeader)
+	if len(limits) == 0 {
+		return nil
+	}
+
+	windows := make([]Window, 0, len(limits))
+	for _, limit := range limits {
+		windows = append(windows, rl.updateRateLimit(methodID, limit.Limit, limit.Count, limit.Duration, bucket, conservePercent, isRegionHeader))
+	}
+
+	rl.backend.Update(bucket, windows)
+	return windows
+}
+
+// ParseRateLimitHeaders parses a pair of rate limit headers, such as X-App-Rate-Limit and
+// X-App-Rate-Limit-Count, into a window per limit, with the count of the same duration as its Count.
+func ParseRateLimitHeaders(limitHeader, countHeader string) []Window {
+	windows := parseRateLimitHeader(limitHeader)
+	if len(windows) == 0 {
+		return nil
+	}
+
+	counts := make(map[time.Duration]int)
+	for _, count := range parseRateLimitHeader(countHeader) {
+		counts[count.Duration] = count.Limit
+	}
+
+	for i := range windows {
+		windows[i].Count = counts[windows[i].Duration]
+	}
+
+	return windows
+}
+
+// parseRateLimitHeader parses a rate limit header such as "20:1,100:120", made of comma-separated
+// "value:seconds" pairs, into a window per pair with the value as its Limit. Malformed pairs,
+// and pairs whose window duration was already seen, are skipped.
+func parseRateLimitHeader(header string) []Window {
+	var windows []Window
+	seen := make(map[time.Duration]bool)
+
+	for _, pair := range strings.Split(header, ",") {
+		value, seconds, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			continue
+		}
+
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit < 0 {
+			continue
+		}
+
+		duration, err := strconv.Atoi(strings.TrimSpace(seconds))
+		if err != nil || duration <= 0 {
+			continue
+		}
+
+		window := Window{
+			Limit:    limit,
+			Duration: time.Duration(duration) * time.Second,
+		}
 
-	limit, _ := strconv.Atoi(limitSplit[0])
-	limitTimeout, _ := strconv.Atoi(limitSplit[1])
-	count, _ := strconv.Atoi(countSplit[0])
+		if seen[window.Duration] {
+			continue
+		}
+
+		seen[window.Duration] = true
+		windows = append(windows, window)
+	}
+
+	return windows
+}
 
+func (rl *RateLimiter) updateRateLimit(methodID MethodID, limit, count int, duration time.Duration, bucket string, conservePercent int, isRegionHeader bool) Window {
 	var limitWithConservation int = limit
 
 	var useConservation bool = false
@@ -304,49 +1092,44 @@ func (rl *RateLimiter) updateRateLimit(methodID MethodID, limitInfo, countInfo s
 		limitWithConservation = limit - 1
 	}
 
-	// If the limit has been reached, block the limiter channel until the limit resets
-	if count >= limitWithConservation && time.Now().After(*blockedUntil) {
-		*blockedUntil = time.Now().Add(time.Duration(limitTimeout) * time.Second)
+	// If the limit has been reached, block the bucket until the limit resets
+	if count >= limitWithConservation {
+		if blockedUntil, err := rl.backend.BlockedUntil(bucket); err == nil && rl.clock.Now().After(blockedUntil) {
+			rl.backend.Block(bucket, rl.clock.Now().Add(duration))
+		}
 	}
 
-	// Resize the limiter channel if needed
-	if limiterChannel.Capacity() != limitWithConservation {
-		limiterChannel.SetCapacity(limitWithConservation)
+	return Window{
+		Limit:    limitWithConservation,
+		Count:    count,
+		Duration: duration,
 	}
-
-	// Add a goroutine to remove an element from the limiter channel after the limit timeout
-	go func() {
-		time.Sleep(time.Duration(limitTimeout) * time.Second)
-		limiterChannel.Release()
-	}()
 }
 
-func isBadRequest(resp *http.Response) bool {
-	return resp == nil || resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden ||
-		resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusUnsupportedMediaType
-}
+func endSpan(span telemetry.Span, err error) {
+	if span == nil {
+		return
+	}
 
-func getShortAndLongLimits(limitHeader string) (string, string) {
-	limits := strings.Split(limitHeader, ",")
-	return limits[0], limits[1]
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	span.End()
 }
 
-func handleRateLimitedResponse(resp *http.Response, regionLimiter *RateLimit, methodLimiter *RateLimit) {
+// handleRateLimitedResponse blocks the bucket that was rate limited and returns how long to wait before retrying.
+func (rl *RateLimiter) handleRateLimitedResponse(resp *http.Response, regionBucket, methodBucket string) time.Duration {
 	retryAfterHeader := resp.Header.Get("Retry-After")
 	rateLimitTypeHeader := resp.Header.Get("X-Rate-Limit-Type")
 	retryAfter, _ := strconv.Atoi(retryAfterHeader)
 	retryAfterDuration := time.Duration(retryAfter) * time.Second
 
 	if rateLimitTypeHeader == "application" {
-		regionLimiter.blockedUntil = time.Now().Add(retryAfterDuration)
+		rl.backend.Block(regionBucket, rl.clock.Now().Add(retryAfterDuration))
 	} else if rateLimitTypeHeader == "method" {
-		methodLimiter.blockedUntil = time.Now().Add(retryAfterDuration)
+		rl.backend.Block(methodBucket, rl.clock.Now().Add(retryAfterDuration))
 	}
 
-	time.Sleep(retryAfterDuration)
-
-	// Remove the request from the limiter channels
-	regionLimiter.shortLimiter.Release()
-	regionLimiter.longLimiter.Release()
-	methodLimiter.shortLimiter.Release()
+	return retryAfterDuration
 }
@@ -0,0 +1,824 @@
+package ratelimiter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter/clock"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/telemetry"
+)
+
+// newTestRateLimiter returns a started rate limiter and a server handling its requests.
+// The configure functions are called before the rate limiter is started.
+func newTestRateLimiter(t *testing.T, handler http.HandlerFunc, configure ...func(rl *RateLimiter)) (*RateLimiter, *httptest.Server) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	rl := NewRateLimiter(make(chan *APIRequest), "RGAPI-test")
+	for _, f := range configure {
+		f(rl)
+	}
+
+	go rl.Start()
+	t.Cleanup(func() {
+		rl.Close(context.Background())
+	})
+
+	return rl, server
+}
+
+// send submits a request and waits for its response. If the request cannot be submitted,
+// the error is reported and returned in the response, so that send can be called from
+// other goroutines than the test's.
+func send(t *testing.T, rl *RateLimiter, req *APIRequest) *APIResponse {
+	t.Helper()
+
+	responses := make(chan *APIResponse, 1)
+	req.Response = responses
+
+	if err := rl.Submit(req); err != nil {
+		t.Errorf("Submit() = %v", err)
+		return &APIResponse{Err: err}
+	}
+
+	return <-responses
+}
+
+func TestResponseBodyReadAfterResponse(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 4<<20)
+	rl, server := newTestRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+
+	for i := 0; i < 5; i++ {
+		res := send(t, rl, &APIRequest{Region: "NA1", MethodID: GetMatch, URL: server.URL})
+		if res.Err != nil {
+			t.Fatalf("response error = %v", res.Err)
+		}
+
+		read, err := io.ReadAll(res.Response.Body)
+		res.Response.Body.Close()
+		if err != nil {
+			t.Fatalf("reading the body: %v", err)
+		}
+
+		if len(read) != len(body) {
+			t.Fatalf("read %d bytes, want %d", len(read), len(body))
+		}
+	}
+}
+
+func TestRetriedResponseKeepsContext(t *testing.T) {
+	var calls int32
+	rl, server := newTestRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte("{}"))
+	})
+
+	res := send(t, rl, &APIRequest{
+		Region:   "NA1",
+		MethodID: GetMatch,
+		URL:      server.URL,
+		RetryPolicy: RetryPolicyFunc(func(attempt RetryAttempt) (bool, time.Duration) {
+			return attempt.Retries == 0, 10 * time.Millisecond
+		}),
+	})
+
+	if res.Err != nil {
+		t.Fatalf("response error = %v", res.Err)
+	}
+
+	defer res.Response.Body.Close()
+
+	if res.Response.StatusCode != http.StatusOK || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("status %d after %d calls, want 200 after 2", res.Response.StatusCode, calls)
+	}
+}
+
+// recordingTracer records the spans it starts.
+type recordingTracer struct {
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	name       string
+	attributes map[string]interface{}
+	ended      bool
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attributes ...telemetry.Attribute) (context.Context, telemetry.Span) {
+	span := &recordedSpan{name: name, attributes: make(map[string]interface{})}
+	span.SetAttributes(attributes...)
+
+	t.mutex.Lock()
+	t.spans = append(t.spans, span)
+	t.mutex.Unlock()
+
+	return ctx, span
+}
+
+// named returns the spans with the given name.
+func (t *recordingTracer) named(name string) []*recordedSpan {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var spans []*recordedSpan
+	for _, span := range t.spans {
+		if span.name == name {
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
+
+func (s *recordedSpan) SetAttributes(attributes ...telemetry.Attribute) {
+	for _, attribute := range attributes {
+		s.attributes[attribute.Key] = attribute.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) {}
+
+func (s *recordedSpan) End() {
+	s.ended = true
+}
+
+func TestRequestSpans(t *testing.T) {
+	var calls int32
+	tracer := &recordingTracer{}
+	rl, server := newTestRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte("{}"))
+	}, func(rl *RateLimiter) {
+		rl.SetTracer(tracer)
+	})
+
+	res := send(t, rl, &APIRequest{
+		Context:  context.Background(),
+		Region:   "NA1",
+		MethodID: GetSummonerByPuuid,
+		URL:      server.URL,
+		RetryPolicy: RetryPolicyFunc(func(attempt RetryAttempt) (bool, time.Duration) {
+			return attempt.Retries == 0, 0
+		}),
+	})
+
+	if res.Err != nil {
+		t.Fatalf("response error = %v", res.Err)
+	}
+
+	res.Response.Body.Close()
+
+	// Each attempt has its own span, with the status code of its response
+	spans := tracer.named(telemetry.SpanRequest)
+	if len(spans) != 2 {
+		t.Fatalf("started %d request spans, want 2", len(spans))
+	}
+
+	for i, status := range []int{http.StatusServiceUnavailable, http.StatusOK} {
+		span := spans[i]
+		if !span.ended || span.attributes[telemetry.AttrStatus] != status || span.attributes[telemetry.AttrRetries] != i ||
+			span.attributes[telemetry.AttrMethodID] != GetSummonerByPuuid.String() {
+			t.Errorf("request span %d = %v (ended %t), want status %d after %d retries", i, span.attributes, span.ended, status, i)
+		}
+	}
+}
+
+// slowBackend takes about a Redis round trip to obtain slots.
+type slowBackend struct {
+	Backend
+}
+
+func (b slowBackend) TryObtain(bucket string, initial []Window) (time.Duration, error) {
+	time.Sleep(2 * time.Millisecond)
+	return b.Backend.TryObtain(bucket, initial)
+}
+
+// rateLimitHandler responds with the given application and method rate limits.
+func rateLimitHandler(appLimit, methodLimit string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-App-Rate-Limit", appLimit)
+		w.Header().Set("X-App-Rate-Limit-Count", "1:10")
+		w.Header().Set("X-Method-Rate-Limit", methodLimit)
+		w.Header().Set("X-Method-Rate-Limit-Count", "1:10")
+		w.Write([]byte("{}"))
+	}
+}
+
+func TestNonBlockingRequestsWithRoom(t *testing.T) {
+	rl, server := newTestRateLimiter(t, rateLimitHandler("500:10", "500:10"), func(rl *RateLimiter) {
+		rl.SetBackend(slowBackend{NewMemoryBackend()})
+	})
+
+	// Learn the real limits, which have room for every request below
+	res := send(t, rl, &APIRequest{Region: "NA1", MethodID: GetMatch, URL: server.URL})
+	if res.Err != nil {
+		t.Fatalf("response error = %v", res.Err)
+	}
+
+	res.Response.Body.Close()
+
+	var wg sync.WaitGroup
+	var blocked int32
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			res := send(t, rl, &APIRequest{Region: "NA1", MethodID: GetMatch, URL: server.URL, NonBlocking: true})
+			if res.Err != nil {
+				atomic.AddInt32(&blocked, 1)
+				return
+			}
+
+			res.Response.Body.Close()
+		}()
+	}
+
+	wg.Wait()
+
+	if blocked != 0 {
+		t.Errorf("%d of 100 non-blocking requests failed with room in the bucket", blocked)
+	}
+}
+
+func TestNonBlockingRequestWhenFull(t *testing.T) {
+	rl, server := newTestRateLimiter(t, rateLimitHandler("500:10", "1:10"))
+
+	res := send(t, rl, &APIRequest{Region: "NA1", MethodID: GetMatch, URL: server.URL})
+	if res.Err != nil {
+		t.Fatalf("response error = %v", res.Err)
+	}
+
+	res.Response.Body.Close()
+
+	res = send(t, rl, &APIRequest{Region: "NA1", MethodID: GetMatch, URL: server.URL, NonBlocking: true})
+
+	var wouldBlock ErrWouldBlock
+	if !errors.As(res.Err, &wouldBlock) || wouldBlock.Wait <= 0 {
+		t.Fatalf("response error = %v, want ErrWouldBlock with a wait", res.Err)
+	}
+}
+
+func TestParseRateLimitHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  string
+		count  string
+		expect []Window
+	}{
+		{
+			name:   "one window",
+			limit:  "2000:10",
+			count:  "7:10",
+			expect: []Window{{Limit: 2000, Count: 7, Duration: 10 * time.Second}},
+		},
+		{
+			name:  "two windows",
+			limit: "20:1,100:120",
+			count: "1:1,42:120",
+			expect: []Window{
+				{Limit: 20, Count: 1, Duration: time.Second},
+				{Limit: 100, Count: 42, Duration: 120 * time.Second},
+			},
+		},
+		{
+			name:  "three windows",
+			limit: "500:10,30000:600,500000:3600",
+			count: "3:10,150:600,1200:3600",
+			expect: []Window{
+				{Limit: 500, Count: 3, Duration: 10 * time.Second},
+				{Limit: 30000, Count: 150, Duration: 600 * time.Second},
+				{Limit: 500000, Count: 1200, Duration: 3600 * time.Second},
+			},
+		},
+		{
+			name:  "counts in another order",
+			limit: "20:1,100:120",
+			count: "42:120,1:1",
+			expect: []Window{
+				{Limit: 20, Count: 1, Duration: time.Second},
+				{Limit: 100, Count: 42, Duration: 120 * time.Second},
+			},
+		},
+		{
+			name:  "windows in another order",
+			limit: "100:120,20:1",
+			count: "1:1,42:120",
+			expect: []Window{
+				{Limit: 100, Count: 42, Duration: 120 * time.Second},
+				{Limit: 20, Count: 1, Duration: time.Second},
+			},
+		},
+		{
+			name:  "missing count header",
+			limit: "20:1,100:120",
+			count: "",
+			expect: []Window{
+				{Limit: 20, Duration: time.Second},
+				{Limit: 100, Duration: 120 * time.Second},
+			},
+		},
+		{
+			name:  "missing count for a window",
+			limit: "20:1,100:120",
+			count: "1:1",
+			expect: []Window{
+				{Limit: 20, Count: 1, Duration: time.Second},
+				{Limit: 100, Duration: 120 * time.Second},
+			},
+		},
+		{
+			name:  "duplicate windows",
+			limit: "20:1,25:1,100:120",
+			count: "1:1,2:1,42:120",
+			expect: []Window{
+				{Limit: 20, Count: 1, Duration: time.Second},
+				{Limit: 100, Count: 42, Duration: 120 * time.Second},
+			},
+		},
+		{
+			name:  "spaces around pairs",
+			limit: " 20 : 1 , 100:120 ",
+			count: "1:1, 42:120",
+			expect: []Window{
+				{Limit: 20, Count: 1, Duration: time.Second},
+				{Limit: 100, Count: 42, Duration: 120 * time.Second},
+			},
+		},
+		{
+			name:   "malformed pairs",
+			limit:  "20,abc:1,20:x,-1:5,5:0,5:-10,:,,100:120",
+			count:  "garbage,42:120",
+			expect: []Window{{Limit: 100, Count: 42, Duration: 120 * time.Second}},
+		},
+		{
+			name:   "empty limit header",
+			limit:  "",
+			count:  "1:1",
+			expect: nil,
+		},
+		{
+			name:   "only malformed pairs",
+			limit:  "a:b,c",
+			count:  "1:1",
+			expect: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			windows := ParseRateLimitHeaders(test.limit, test.count)
+			if !reflect.DeepEqual(windows, test.expect) {
+				t.Errorf("ParseRateLimitHeaders(%q, %q) = %+v, want %+v", test.limit, test.count, windows, test.expect)
+			}
+		})
+	}
+}
+
+func TestParseRateLimitHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		expect []Window
+	}{
+		{"", nil},
+		{"20:1", []Window{{Limit: 20, Duration: time.Second}}},
+		{"0:1", []Window{{Limit: 0, Duration: time.Second}}},
+		{"20:1,100:120", []Window{{Limit: 20, Duration: time.Second}, {Limit: 100, Duration: 120 * time.Second}}},
+		{"20:1,20:1", []Window{{Limit: 20, Duration: time.Second}}},
+		{"20:1:5", nil},
+		{"20;1", nil},
+		{"1.5:1", nil},
+	}
+
+	for _, test := range tests {
+		if windows := parseRateLimitHeader(test.header); !reflect.DeepEqual(windows, test.expect) {
+			t.Errorf("parseRateLimitHeader(%q) = %+v, want %+v", test.header, windows, test.expect)
+		}
+	}
+}
+
+// newFakeClockRateLimiter returns a started rate limiter whose time only passes when the
+// returned clock is advanced, and a server handling its requests. The configure functions
+// are called before the rate limiter is started.
+func newFakeClockRateLimiter(t *testing.T, handler http.HandlerFunc, configure ...func(rl *RateLimiter)) (*RateLimiter, *httptest.Server, *clock.Fake) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+
+	rl := NewRateLimiter(make(chan *APIRequest), "RGAPI-test")
+	rl.SetClock(fake)
+	for _, f := range configure {
+		f(rl)
+	}
+
+	go rl.Start()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		rl.Close(ctx)
+	})
+
+	return rl, server, fake
+}
+
+// submit submits a request for GetMatch and returns the channel its response is sent to.
+func submit(t *testing.T, rl *RateLimiter, server *httptest.Server) <-chan *APIResponse {
+	t.Helper()
+
+	responses := make(chan *APIResponse, 1)
+	if err := rl.Submit(&APIRequest{Region: "NA1", MethodID: GetMatch, URL: server.URL, Response: responses}); err != nil {
+		t.Fatalf("Submit() = %v", err)
+	}
+
+	return responses
+}
+
+// receive returns the response sent to the channel, failing if it is not sent promptly.
+func receive(t *testing.T, responses <-chan *APIResponse) *APIResponse {
+	t.Helper()
+
+	select {
+	case res := <-responses:
+		if res.Err != nil {
+			t.Fatalf("response error = %v", res.Err)
+		}
+
+		res.Response.Body.Close()
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("no response without advancing the clock")
+		return nil
+	}
+}
+
+// waitForTimer waits until the code under test waits on a timer of the fake clock, and checks
+// that no response was sent meanwhile.
+func waitForTimer(t *testing.T, fake *clock.Fake, responses <-chan *APIResponse) {
+	t.Helper()
+
+	for start := time.Now(); fake.Timers() == 0; {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the request did not wait")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case <-responses:
+		t.Fatal("response sent before the clock was advanced")
+	default:
+	}
+}
+
+func TestWindowResetWithFakeClock(t *testing.T) {
+	rl, server, fake := newFakeClockRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Method-Rate-Limit", "2:10")
+		w.Header().Set("X-Method-Rate-Limit-Count", "1:10")
+		w.Write([]byte("{}"))
+	})
+
+	receive(t, submit(t, rl, server))
+
+	// Without conservation one slot is kept spare, so the limit of 2 is reached
+	responses := submit(t, rl, server)
+	waitForTimer(t, fake, responses)
+
+	fake.Advance(9 * time.Second)
+	select {
+	case <-responses:
+		t.Fatal("response sent before the window reset")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	fake.Advance(time.Second)
+	receive(t, responses)
+}
+
+func TestRetryAfterWithFakeClock(t *testing.T) {
+	var calls int32
+	rl, server, fake := newFakeClockRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "5")
+			w.Header().Set("X-Rate-Limit-Type", "method")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.Write([]byte("{}"))
+	})
+
+	responses := submit(t, rl, server)
+	waitForTimer(t, fake, responses)
+
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Fatalf("%d calls before Retry-After passed, want 1", calls)
+	}
+
+	// The method is blocked for the same time, so other requests wait too
+	if until, _ := rl.backend.BlockedUntil(methodBucket("", "NA1", GetMatch)); !until.Equal(fake.Now().Add(5 * time.Second)) {
+		t.Errorf("method blocked until %v, want 5s after %v", until, fake.Now())
+	}
+
+	fake.Advance(5 * time.Second)
+
+	if res := receive(t, responses); res.Response.StatusCode != http.StatusOK || atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("status %d after %d calls, want 200 after 2", res.Response.StatusCode, calls)
+	}
+}
+
+func TestUsageConservationWithFakeClock(t *testing.T) {
+	tests := []struct {
+		name          string
+		conserveUsage ConserveUsage
+		immediate     int
+	}{
+		{"no conservation", ConserveUsage{}, 9},
+		{"50 percent", ConserveUsage{MethodPercent: 50}, 5},
+		{"80 percent", ConserveUsage{MethodPercent: 80}, 2},
+		{"ignored method", ConserveUsage{MethodPercent: 50, IgnoreLimits: []MethodID{GetMatch}}, 9},
+		{"region percent only", ConserveUsage{RegionPercent: 50}, 9},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls int32
+			rl, server, fake := newFakeClockRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Method-Rate-Limit", "10:10")
+				w.Header().Set("X-Method-Rate-Limit-Count", fmt.Sprintf("%d:10", atomic.AddInt32(&calls, 1)))
+				w.Write([]byte("{}"))
+			})
+
+			rl.SetUsageConservation(test.conserveUsage)
+
+			for i := 0; i < test.immediate; i++ {
+				receive(t, submit(t, rl, server))
+			}
+
+			responses := submit(t, rl, server)
+			waitForTimer(t, fake, responses)
+
+			fake.Advance(10 * time.Second)
+			receive(t, responses)
+		})
+	}
+}
+
+// failingStore is a StateStore that cannot load or save.
+type failingStore struct {
+	err error
+}
+
+func (s failingStore) Load() (*Snapshot, error) {
+	return nil, s.err
+}
+
+func (s failingStore) Save(snapshot Snapshot) error {
+	return s.err
+}
+
+func TestStateStoreErrorsAreObserved(t *testing.T) {
+	storeErr := errors.New("state store unavailable")
+
+	rl := NewRateLimiter(make(chan *APIRequest), "RGAPI-test")
+	rl.SetStateStore(failingStore{err: storeErr}, 10*time.Millisecond)
+
+	// The observer is set after the state store, as options may be in any order
+	events := make(chan Event, 16)
+	rl.SetObserver(ObserverFunc(func(event Event) {
+		select {
+		case events <- event:
+		default:
+		}
+	}), false)
+
+	go rl.Start()
+	defer rl.Close(context.Background())
+
+	// The restore error is sent when the rate limiter starts, then each failed periodic save
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-events:
+			if event.Type != EventStateStoreError || event.Err != storeErr {
+				t.Fatalf("event %d = %+v, want %s with %v", i, event, EventStateStoreError, storeErr)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %s event %d", EventStateStoreError, i)
+		}
+	}
+}
+
+// memoryStore is a StateStore that keeps the snapshot in memory, and records whether saves
+// overlapped. If block is set, each save sends to saving and waits for block.
+type memoryStore struct {
+	mutex      sync.Mutex
+	snapshot   *Snapshot
+	saves      int
+	active     int
+	overlapped bool
+	saving     chan struct{}
+	block      chan struct{}
+}
+
+func (s *memoryStore) Load() (*Snapshot, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.snapshot, nil
+}
+
+func (s *memoryStore) Save(snapshot Snapshot) error {
+	s.mutex.Lock()
+	s.active++
+	s.overlapped = s.overlapped || s.active > 1
+	s.mutex.Unlock()
+
+	if s.block != nil {
+		s.saving <- struct{}{}
+		<-s.block
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.snapshot = &snapshot
+	s.saves++
+	s.active--
+	return nil
+}
+
+// count returns the number of saves, and whether any of them overlapped.
+func (s *memoryStore) count() (int, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.saves, s.overlapped
+}
+
+func TestStateStoreRestoresIntoLaterBackend(t *testing.T) {
+	saved := NewMemoryBackend()
+	saved.Update("NA1", []Window{{Limit: 100, Count: 40, Duration: 2 * time.Minute}})
+
+	snapshot, err := saved.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() = %v", err)
+	}
+
+	// The state store is set before the backend, as options may be in any order
+	backend := NewMemoryBackend()
+	rl := NewRateLimiter(make(chan *APIRequest), "RGAPI-test")
+	rl.SetStateStore(&memoryStore{snapshot: &snapshot}, 0)
+	rl.SetBackend(backend)
+
+	go rl.Start()
+	defer rl.Close(context.Background())
+
+	for start := time.Now(); ; time.Sleep(time.Millisecond) {
+		if state, _ := backend.State("NA1"); len(state.Windows) == 1 && state.Windows[0].Count == 40 {
+			break
+		}
+
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the state was not restored into the backend")
+		}
+	}
+}
+
+func TestCloseWaitsForPeriodicSave(t *testing.T) {
+	store := &memoryStore{saving: make(chan struct{}, 2), block: make(chan struct{})}
+	rl, _, fake := newFakeClockRateLimiter(t, nil, func(rl *RateLimiter) {
+		rl.SetStateStore(store, time.Minute)
+	})
+
+	for start := time.Now(); fake.Timers() == 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the state is not saved periodically")
+		}
+	}
+
+	fake.Advance(time.Minute)
+	<-store.saving
+
+	closed := make(chan error)
+	go func() {
+		closed <- rl.Close(context.Background())
+	}()
+
+	select {
+	case err := <-closed:
+		t.Fatalf("Close() = %v while the state was being saved", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The periodic save finishes, then the state is saved a last time
+	close(store.block)
+
+	if err := <-closed; err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	if saves, overlapped := store.count(); saves != 2 || overlapped {
+		t.Errorf("state saved %d times, overlapping: %v, want 2 saves one after the other", saves, overlapped)
+	}
+}
+
+func TestCloseBeforeStartDoesNotSave(t *testing.T) {
+	snapshot := Snapshot{Buckets: map[string]BucketSnapshot{"NA1": {}}}
+	store := &memoryStore{snapshot: &snapshot}
+
+	rl := NewRateLimiter(make(chan *APIRequest), "RGAPI-test")
+	rl.SetStateStore(store, 0)
+
+	if err := rl.Close(context.Background()); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	if saves, _ := store.count(); saves != 0 {
+		t.Errorf("state saved %d times before it was restored, want 0", saves)
+	}
+}
+
+func TestRequestCanceledWhileQueued(t *testing.T) {
+	rl, server, fake := newFakeClockRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	})
+
+	// The initial method limit is 5 requests every 10 seconds, so the 6th request waits for the
+	// method's window after it took a slot in the region's windows
+	for i := 0; i < 5; i++ {
+		receive(t, submit(t, rl, server))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	responses := make(chan *APIResponse, 1)
+	if err := rl.Submit(&APIRequest{Context: ctx, Region: "NA1", MethodID: GetMatch, URL: server.URL, Response: responses}); err != nil {
+		t.Fatalf("Submit() = %v", err)
+	}
+
+	waitForTimer(t, fake, responses)
+
+	stats, err := rl.Stats()
+	if err != nil || stats.Regions["NA1"].Queued != 1 || stats.Methods["NA1"][GetMatch].Queued != 1 {
+		t.Fatalf("Stats() = %+v, %v, want 1 request queued", stats, err)
+	}
+
+	cancel()
+
+	select {
+	case res := <-responses:
+		if res.Err != context.Canceled {
+			t.Fatalf("response error = %v, want %v", res.Err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no response after the request was canceled")
+	}
+
+	// The request no longer counts as queued, and its slot in the region's windows is released
+	stats, err = rl.Stats()
+	if err != nil || stats.Regions["NA1"].Queued != 0 || stats.Methods["NA1"][GetMatch].Queued != 0 {
+		t.Errorf("Stats() after the request was canceled = %+v, %v, want no requests queued", stats, err)
+	}
+
+	if windows := stats.Regions["NA1"].Windows; len(windows) != 2 {
+		t.Errorf("region windows = %+v, want the 2 initial windows", windows)
+	}
+
+	for _, window := range stats.Regions["NA1"].Windows {
+		if window.Count != 5 {
+			t.Errorf("region window %v has %d slots taken, want 5", window.Duration, window.Count)
+		}
+	}
+
+	if pending := rl.Pending(); pending[""] != 0 {
+		t.Errorf("Pending() = %v, want no requests", pending)
+	}
+}
@@ -0,0 +1,319 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter/clock"
+	"github.com/Kinveil/Riot-API-Golang/internal/redis"
+)
+
+// RedisOptions configures a RedisBackend.
+type RedisOptions struct {
+	// Addr is the host:port address of the Redis server.
+	Addr string
+
+	// Password is used to authenticate with the server, if set.
+	Password string
+
+	// DB is the database to select, if set.
+	DB int
+
+	// Prefix is prepended to every key. Defaults to "riot-ratelimit".
+	// Processes only share limits if they use the same prefix.
+	Prefix string
+
+	// PoolSize is the maximum number of idle connections. Defaults to 10.
+	PoolSize int
+
+	// PollInterval is the longest time to wait before checking a full bucket again. Defaults to 1 second.
+	PollInterval time.Duration
+}
+
+// RedisBackend is a Backend that stores the rate limit state in a Redis server,
+// so that several processes using the same API key can share their limits.
+//
+// Each window is stored as a counter that expires when the window ends, and the
+// learned limits and blocked time of each bucket are shared through the server.
+type RedisBackend struct {
+	client       *redis.Client
+	prefix       string
+	pollInterval time.Duration
+	clock        clock.Clock
+}
+
+func NewRedisBackend(opts RedisOptions) *RedisBackend {
+	if opts.Prefix == "" {
+		opts.Prefix = "riot-ratelimit"
+	}
+
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+
+	return &RedisBackend{
+		client: redis.NewClient(redis.Options{
+			Addr:     opts.Addr,
+			Password: opts.Password,
+			DB:       opts.DB,
+			PoolSize: opts.PoolSize,
+		}),
+		prefix:       opts.Prefix,
+		pollInterval: opts.PollInterval,
+		clock:        clock.Real,
+	}
+}
+
+// SetClock sets the clock used to time blocks and the waits between checks of a full bucket.
+// The windows themselves expire on the Redis server, in its time. It must be called before the
+// backend is used.
+func (b *RedisBackend) SetClock(c clock.Clock) {
+	b.clock = c
+}
+
+// Close closes the connections to the Redis server.
+func (b *RedisBackend) Close() error {
+	return b.client.Close()
+}
+
+func (b *RedisBackend) key(bucket string) string {
+	return b.prefix + ":" + bucket
+}
+
+// obtainScript increments the count of every window of the bucket if none of them
+// are full. Otherwise, it returns the number of milliseconds until a window resets.
+const obtainScript = `
+local limits = redis.call('HGETALL', KEYS[1] .. ':limits')
+if #limits == 0 then
+	for i = 1, #ARGV, 2 do
+		redis.call('HSET', KEYS[1] .. ':limits', ARGV[i + 1], ARGV[i])
+	end
+	limits = redis.call('HGETALL', KEYS[1] .. ':limits')
+end
+
+local wait = 0
+for i = 1, #limits, 2 do
+	local key = KEYS[1] .. ':count:' .. limits[i]
+	local count = tonumber(redis.call('GET', key) or '0')
+	if count >= tonumber(limits[i + 1]) then
+		local ttl = redis.call('PTTL', key)
+		if ttl < 0 then
+			ttl = tonumber(limits[i])
+		end
+		if ttl > wait then
+			wait = ttl
+		end
+	end
+end
+
+if wait > 0 then
+	return wait
+end
+
+for i = 1, #limits, 2 do
+	local key = KEYS[1] .. ':count:' .. limits[i]
+	if redis.call('INCR', key) == 1 then
+		redis.call('PEXPIRE', key, limits[i])
+	end
+end
+
+return 0
+`
+
+// updateScript replaces the limits of the bucket and raises the count of each window
+// to the count reported by Riot. The counts of the windows that are no longer limited
+// are deleted, so that they do not count towards a window of the same duration later.
+const updateScript = `
+local windows = {}
+for i = 1, #ARGV, 3 do
+	windows[ARGV[i + 1]] = true
+end
+
+for _, duration in ipairs(redis.call('HKEYS', KEYS[1] .. ':limits')) do
+	if not windows[duration] then
+		redis.call('DEL', KEYS[1] .. ':count:' .. duration)
+	end
+end
+
+redis.call('DEL', KEYS[1] .. ':limits')
+
+for i = 1, #ARGV, 3 do
+	redis.call('HSET', KEYS[1] .. ':limits', ARGV[i + 1], ARGV[i])
+
+	local key = KEYS[1] .. ':count:' .. ARGV[i + 1]
+	local count = tonumber(ARGV[i + 2])
+	local current = tonumber(redis.call('GET', key) or '0')
+	if count > current then
+		redis.call('INCRBY', key, count - current)
+		if current == 0 then
+			redis.call('PEXPIRE', key, ARGV[i + 1])
+		end
+	end
+end
+
+return 0
+`
+
+func (b *RedisBackend) Obtain(ctx context.Context, bucket string, initial []Window) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		delay, err := b.tryObtain(ctx, bucket, initial)
+		if err != nil {
+			return err
+		}
+
+		if delay <= 0 {
+			return nil
+		}
+
+		if delay > b.pollInterval {
+			delay = b.pollInterval
+		}
+
+		timer := b.clock.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+	}
+}
+
+func (b *RedisBackend) TryObtain(bucket string, initial []Window) (time.Duration, error) {
+	return b.tryObtain(context.Background(), bucket, initial)
+}
+
+func (b *RedisBackend) tryObtain(ctx context.Context, bucket string, initial []Window) (time.Duration, error) {
+	args := []interface{}{"EVAL", obtainScript, 1, b.key(bucket)}
+	for _, window := range initial {
+		args = append(args, window.Limit, window.Duration.Milliseconds())
+	}
+
+	reply, err := b.client.Do(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	wait, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("ratelimiter: unexpected reply %v from redis", reply)
+	}
+
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+// releaseScript decrements the count of every window of the bucket.
+const releaseScript = `
+local limits = redis.call('HGETALL', KEYS[1] .. ':limits')
+for i = 1, #limits, 2 do
+	local key = KEYS[1] .. ':count:' .. limits[i]
+	if tonumber(redis.call('GET', key) or '0') > 0 then
+		redis.call('DECR', key)
+	end
+end
+
+return 0
+`
+
+func (b *RedisBackend) Release(bucket string) error {
+	_, err := b.client.Do(context.Background(), "EVAL", releaseScript, 1, b.key(bucket))
+	return err
+}
+
+func (b *RedisBackend) Update(bucket string, windows []Window) error {
+	args := []interface{}{"EVAL", updateScript, 1, b.key(bucket)}
+	for _, window := range windows {
+		args = append(args, window.Limit, window.Duration.Milliseconds(), window.Count)
+	}
+
+	_, err := b.client.Do(context.Background(), args...)
+	return err
+}
+
+func (b *RedisBackend) BlockedUntil(bucket string) (time.Time, error) {
+	reply, err := b.client.Do(context.Background(), "GET", b.key(bucket)+":blocked")
+	if err != nil || reply == nil {
+		return time.Time{}, err
+	}
+
+	s, _ := reply.(string)
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.UnixMilli(ms), nil
+}
+
+func (b *RedisBackend) Block(bucket string, until time.Time) error {
+	ttl := clock.Until(b.clock, until).Milliseconds()
+	if ttl <= 0 {
+		return nil
+	}
+
+	_, err := b.client.Do(context.Background(), "SET", b.key(bucket)+":blocked", until.UnixMilli(), "PX", ttl)
+	return err
+}
+
+func (b *RedisBackend) State(bucket string) (BucketState, error) {
+	var state BucketState
+
+	blockedUntil, err := b.BlockedUntil(bucket)
+	if err != nil {
+		return state, err
+	}
+
+	state.BlockedUntil = blockedUntil
+
+	reply, err := b.client.Do(context.Background(), "HGETALL", b.key(bucket)+":limits")
+	if err != nil {
+		return state, err
+	}
+
+	limits, _ := reply.([]interface{})
+	for i := 0; i+1 < len(limits); i += 2 {
+		durationField, _ := limits[i].(string)
+		limitField, _ := limits[i+1].(string)
+
+		durationMs, _ := strconv.ParseInt(durationField, 10, 64)
+		limit, _ := strconv.Atoi(limitField)
+
+		reply, err := b.client.Do(context.Background(), "GET", b.key(bucket)+":count:"+durationField)
+		if err != nil {
+			return state, err
+		}
+
+		countField, _ := reply.(string)
+		count, _ := strconv.Atoi(countField)
+
+		window := Window{
+			Limit:    limit,
+			Count:    count,
+			Duration: time.Duration(durationMs) * time.Millisecond,
+		}
+
+		reply, err = b.client.Do(context.Background(), "PTTL", b.key(bucket)+":count:"+durationField)
+		if err != nil {
+			return state, err
+		}
+
+		if ttl, ok := reply.(int64); ok && ttl > 0 {
+			window.ResetAt = b.clock.Now().Add(time.Duration(ttl) * time.Millisecond)
+		}
+
+		state.Windows = append(state.Windows, window)
+	}
+
+	sort.Slice(state.Windows, func(i, j int) bool {
+		return state.Windows[i].Duration < state.Windows[j].Duration
+	})
+
+	return state, nil
+}
@@ -0,0 +1,100 @@
+package ratelimiter
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryAttempt describes a failed request that may be retried.
+type RetryAttempt struct {
+	MethodID MethodID
+
+	// Retries is the number of times the request has already been retried.
+	Retries int
+
+	// StatusCode is the status code of the response, or 0 if no response was received.
+	StatusCode int
+
+	// Err is the error that prevented a response from being received, if any.
+	Err error
+
+	// RetryAfter is the Retry-After header of a 429 response, or 0 if there is none.
+	RetryAfter time.Duration
+}
+
+// RetryPolicy decides whether a failed request is retried, and how long to wait before retrying it.
+// Requests are never retried more than the rate limiter's maximum number of retries.
+type RetryPolicy interface {
+	Retry(attempt RetryAttempt) (retry bool, delay time.Duration)
+}
+
+// RetryPolicyFunc adapts a function to a RetryPolicy.
+type RetryPolicyFunc func(attempt RetryAttempt) (bool, time.Duration)
+
+func (f RetryPolicyFunc) Retry(attempt RetryAttempt) (bool, time.Duration) {
+	return f(attempt)
+}
+
+// ExponentialBackoff retries 429 responses after their Retry-After delay, and 408 responses,
+// server errors and network errors after a random delay of up to BaseDelay * 2^Retries, capped
+// at MaxDelay. Other responses are not retried, since sending the same request again would fail
+// the same way.
+type ExponentialBackoff struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+func (b ExponentialBackoff) Retry(attempt RetryAttempt) (bool, time.Duration) {
+	if attempt.StatusCode == http.StatusTooManyRequests && attempt.RetryAfter > 0 {
+		return true, attempt.RetryAfter
+	}
+
+	if attempt.Err == nil && attempt.StatusCode != http.StatusTooManyRequests && attempt.StatusCode != http.StatusRequestTimeout &&
+		attempt.StatusCode < 500 {
+		return false, 0
+	}
+
+	return true, b.delay(attempt.Retries)
+}
+
+// delay returns a random delay of up to BaseDelay * 2^retries, capped at MaxDelay.
+func (b ExponentialBackoff) delay(retries int) time.Duration {
+	ceiling := b.MaxDelay
+	if retries < 32 && b.BaseDelay<<retries > 0 && b.BaseDelay<<retries < ceiling {
+		ceiling = b.BaseDelay << retries
+	}
+
+	if ceiling <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// NoServerErrorRetries wraps a policy so that requests to the given methods are not retried
+// after a server error. This suits endpoints such as GetMatch, where Riot returns server
+// errors for some matches every time they are requested.
+func NoServerErrorRetries(policy RetryPolicy, methodIDs ...MethodID) RetryPolicy {
+	return RetryPolicyFunc(func(attempt RetryAttempt) (bool, time.Duration) {
+		if attempt.StatusCode >= 500 {
+			for _, methodID := range methodIDs {
+				if methodID == attempt.MethodID {
+					return false, 0
+				}
+			}
+		}
+
+		return policy.Retry(attempt)
+	})
+}
+
+// DefaultRetryPolicy is used when no retry policy is set. It backs off exponentially from
+// 1 second up to 1 minute, and does not retry server errors from the Match API.
+var DefaultRetryPolicy = NoServerErrorRetries(
+	ExponentialBackoff{
+		BaseDelay: time.Second,
+		MaxDelay:  time.Minute,
+	},
+	GetMatchlist, GetMatch, GetMatchTimeline,
+)
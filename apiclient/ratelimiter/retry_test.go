@@ -0,0 +1,93 @@
+package ratelimiter
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff{BaseDelay: time.Second, MaxDelay: time.Minute}
+	transportErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name     string
+		attempt  RetryAttempt
+		retry    bool
+		maxDelay time.Duration
+		minDelay time.Duration
+	}{
+		{"429 with Retry-After", RetryAttempt{StatusCode: 429, RetryAfter: 3 * time.Second}, true, 3 * time.Second, 3 * time.Second},
+		{"429 without Retry-After", RetryAttempt{StatusCode: 429, Retries: 2}, true, 4 * time.Second, 0},
+		{"500", RetryAttempt{StatusCode: 500}, true, time.Second, 0},
+		{"503 retried twice", RetryAttempt{StatusCode: 503, Retries: 2}, true, 4 * time.Second, 0},
+		{"408", RetryAttempt{StatusCode: 408}, true, time.Second, 0},
+		{"transport error", RetryAttempt{Err: transportErr, Retries: 1}, true, 2 * time.Second, 0},
+		{"capped delay", RetryAttempt{StatusCode: 500, Retries: 10}, true, time.Minute, 0},
+		{"capped delay after many retries", RetryAttempt{StatusCode: 500, Retries: 100}, true, time.Minute, 0},
+		{"400", RetryAttempt{StatusCode: 400}, false, 0, 0},
+		{"403", RetryAttempt{StatusCode: 403}, false, 0, 0},
+		{"404", RetryAttempt{StatusCode: 404}, false, 0, 0},
+		{"422", RetryAttempt{StatusCode: 422}, false, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The delay is random, so it is checked against its bounds a number of times
+			for i := 0; i < 100; i++ {
+				retry, delay := backoff.Retry(test.attempt)
+				if retry != test.retry || delay < test.minDelay || delay > test.maxDelay {
+					t.Fatalf("Retry(%+v) = %v, %v, want %v with a delay from %v to %v",
+						test.attempt, retry, delay, test.retry, test.minDelay, test.maxDelay)
+				}
+			}
+		})
+	}
+}
+
+func TestNoServerErrorRetries(t *testing.T) {
+	always := RetryPolicyFunc(func(RetryAttempt) (bool, time.Duration) {
+		return true, time.Second
+	})
+
+	policy := NoServerErrorRetries(always, GetMatch, GetMatchTimeline)
+
+	tests := []struct {
+		attempt RetryAttempt
+		retry   bool
+	}{
+		{RetryAttempt{MethodID: GetMatch, StatusCode: 500}, false},
+		{RetryAttempt{MethodID: GetMatchTimeline, StatusCode: 503}, false},
+		{RetryAttempt{MethodID: GetMatch, StatusCode: 429}, true},
+		{RetryAttempt{MethodID: GetMatch, StatusCode: 408}, true},
+		{RetryAttempt{MethodID: GetMatch, Err: errors.New("connection reset")}, true},
+		{RetryAttempt{MethodID: GetMatchlist, StatusCode: 500}, true},
+	}
+
+	for _, test := range tests {
+		if retry, _ := policy.Retry(test.attempt); retry != test.retry {
+			t.Errorf("Retry(%+v) = %v, want %v", test.attempt, retry, test.retry)
+		}
+	}
+}
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	tests := []struct {
+		attempt RetryAttempt
+		retry   bool
+	}{
+		{RetryAttempt{MethodID: GetMatch, StatusCode: 500}, false},
+		{RetryAttempt{MethodID: GetMatchlist, StatusCode: 502}, false},
+		{RetryAttempt{MethodID: GetMatchTimeline, StatusCode: 504}, false},
+		{RetryAttempt{MethodID: GetSummonerByPuuid, StatusCode: 500}, true},
+		{RetryAttempt{MethodID: GetMatch, StatusCode: 429, RetryAfter: time.Second}, true},
+		{RetryAttempt{MethodID: GetMatch, StatusCode: 404}, false},
+	}
+
+	for _, test := range tests {
+		if retry, delay := DefaultRetryPolicy.Retry(test.attempt); retry != test.retry || delay > time.Minute {
+			t.Errorf("DefaultRetryPolicy.Retry(%+v) = %v, %v, want %v", test.attempt, retry, delay, test.retry)
+		}
+	}
+}
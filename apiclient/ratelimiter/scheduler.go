@@ -0,0 +1,122 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter/clock"
+)
+
+// Priority is the priority class of a request. When a slot frees up in a bucket,
+// the waiting request with the highest priority is served first.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+// scheduler lets the requests waiting on a bucket obtain a slot one at a time,
+// in order of priority. Requests with the same priority are served in arrival order.
+//
+// Waiters that have waited longer than starvationTimeout are served before any
+// others, regardless of their priority. A starvationTimeout of 0 disables this.
+type scheduler struct {
+	mutex             sync.Mutex
+	busy              bool
+	waiters           []*waiter
+	starvationTimeout time.Duration
+	clock             clock.Clock
+}
+
+type waiter struct {
+	priority   Priority
+	enqueuedAt time.Time
+	ready      chan struct{}
+}
+
+// acquire blocks until it is the caller's turn to obtain a slot in the bucket.
+func (s *scheduler) acquire(ctx context.Context, priority Priority) error {
+	s.mutex.Lock()
+	if !s.busy && len(s.waiters) == 0 {
+		s.busy = true
+		s.mutex.Unlock()
+		return nil
+	}
+
+	w := &waiter{
+		priority:   priority,
+		enqueuedAt: s.clock.Now(),
+		ready:      make(chan struct{}),
+	}
+
+	s.waiters = append(s.waiters, w)
+	s.mutex.Unlock()
+
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-done:
+	}
+
+	s.mutex.Lock()
+	for i, other := range s.waiters {
+		if other == w {
+			s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+			s.mutex.Unlock()
+			return ctx.Err()
+		}
+	}
+	s.mutex.Unlock()
+
+	// It became the caller's turn at the same time as the context was done
+	s.release()
+	return ctx.Err()
+}
+
+// tryAcquire takes the turn if no other request has it or is waiting for it.
+func (s *scheduler) tryAcquire() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.busy || len(s.waiters) > 0 {
+		return false
+	}
+
+	s.busy = true
+	return true
+}
+
+// release hands the turn to the next waiter, if any.
+func (s *scheduler) release() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.waiters) == 0 {
+		s.busy = false
+		return
+	}
+
+	next := 0
+	for i, w := range s.waiters {
+		if s.starvationTimeout > 0 && clock.Since(s.clock, w.enqueuedAt) >= s.starvationTimeout {
+			next = i
+			break
+		}
+
+		if w.priority > s.waiters[next].priority {
+			next = i
+		}
+	}
+
+	w := s.waiters[next]
+	s.waiters = append(s.waiters[:next], s.waiters[next+1:]...)
+	close(w.ready)
+}
@@ -0,0 +1,126 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter/clock"
+)
+
+// newFakeClockScheduler returns the scheduler of a bucket of a rate limiter with a fake clock,
+// after the turn has been taken, so that the requests acquiring it next are queued.
+func newFakeClockScheduler(t *testing.T, starvationTimeout time.Duration) (*scheduler, *clock.Fake) {
+	t.Helper()
+
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+
+	rl := NewRateLimiter(make(chan *APIRequest), "RGAPI-test")
+	rl.SetClock(fake)
+	rl.SetStarvationTimeout(starvationTimeout)
+
+	s := rl.scheduler("NA1")
+	if err := s.acquire(context.Background(), PriorityNormal); err != nil {
+		t.Fatalf("acquire() = %v", err)
+	}
+
+	return s, fake
+}
+
+// enqueue acquires the turn in a goroutine and waits until it is queued. Once it has the turn,
+// the priority is sent to served, and the turn is released to the next waiter.
+func enqueue(t *testing.T, s *scheduler, priority Priority, served chan<- Priority) {
+	t.Helper()
+
+	s.mutex.Lock()
+	queued := len(s.waiters)
+	s.mutex.Unlock()
+
+	go func() {
+		if err := s.acquire(context.Background(), priority); err != nil {
+			t.Errorf("acquire(%d) = %v", priority, err)
+			return
+		}
+
+		served <- priority
+		s.release()
+	}()
+
+	for start := time.Now(); ; time.Sleep(time.Millisecond) {
+		s.mutex.Lock()
+		n := len(s.waiters)
+		s.mutex.Unlock()
+
+		if n > queued {
+			return
+		}
+
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("acquire(%d) was not queued", priority)
+		}
+	}
+}
+
+// servedOrder releases the turn and returns the priorities of the n queued requests, in the order they were served.
+func servedOrder(t *testing.T, s *scheduler, served <-chan Priority, n int) []Priority {
+	t.Helper()
+
+	s.release()
+
+	var order []Priority
+	for i := 0; i < n; i++ {
+		select {
+		case priority := <-served:
+			order = append(order, priority)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("served %v, want %d requests", order, n)
+		}
+	}
+
+	return order
+}
+
+func TestSchedulerServesHighPriorityFirst(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, 0)
+	served := make(chan Priority, 3)
+
+	enqueue(t, s, PriorityLow, served)
+	enqueue(t, s, PriorityNormal, served)
+	enqueue(t, s, PriorityHigh, served)
+
+	order := servedOrder(t, s, served, 3)
+	if order[0] != PriorityHigh || order[1] != PriorityNormal || order[2] != PriorityLow {
+		t.Errorf("served %v, want high, normal, then low", order)
+	}
+}
+
+func TestSchedulerServesStarvedLowPriority(t *testing.T) {
+	s, fake := newFakeClockScheduler(t, time.Minute)
+	served := make(chan Priority, 2)
+
+	enqueue(t, s, PriorityLow, served)
+
+	// The high priority request has not waited long, so the starved low priority one goes first
+	fake.Advance(time.Minute)
+	enqueue(t, s, PriorityHigh, served)
+
+	order := servedOrder(t, s, served, 2)
+	if order[0] != PriorityLow || order[1] != PriorityHigh {
+		t.Errorf("served %v, want low, then high", order)
+	}
+}
+
+func TestSchedulerServesByPriorityBeforeStarvationTimeout(t *testing.T) {
+	s, fake := newFakeClockScheduler(t, time.Minute)
+	served := make(chan Priority, 2)
+
+	enqueue(t, s, PriorityLow, served)
+
+	fake.Advance(time.Minute - time.Second)
+	enqueue(t, s, PriorityHigh, served)
+
+	order := servedOrder(t, s, served, 2)
+	if order[0] != PriorityHigh || order[1] != PriorityLow {
+		t.Errorf("served %v, want high, then low", order)
+	}
+}
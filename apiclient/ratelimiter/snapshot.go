@@ -0,0 +1,102 @@
+package ratelimiter
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Snapshot is the rate limit state of every bucket at a point in time.
+type Snapshot struct {
+	SavedAt time.Time                 `json:"savedAt"`
+	Buckets map[string]BucketSnapshot `json:"buckets"`
+}
+
+// BucketSnapshot is the saved state of a bucket.
+type BucketSnapshot struct {
+	Windows      []WindowSnapshot `json:"windows"`
+	BlockedUntil time.Time        `json:"blockedUntil"`
+}
+
+// WindowSnapshot is the saved state of a rate limit window.
+// ResetAt is the zero time if the window had not started.
+type WindowSnapshot struct {
+	Limit    int           `json:"limit"`
+	Count    int           `json:"count"`
+	Duration time.Duration `json:"duration"`
+	ResetAt  time.Time     `json:"resetAt"`
+}
+
+// Snapshotter is implemented by backends whose state can be saved and restored,
+// such as MemoryBackend. Backends that already share their state outside of the
+// process, such as RedisBackend, do not need to implement it.
+type Snapshotter interface {
+	Snapshot() (Snapshot, error)
+	Restore(snapshot Snapshot) error
+}
+
+// StateStore saves the rate limiter's state so that it can be restored after a restart.
+type StateStore interface {
+	// Load returns the last saved snapshot, or nil if there is none.
+	Load() (*Snapshot, error)
+
+	// Save replaces the saved snapshot.
+	Save(snapshot Snapshot) error
+}
+
+// FileStore is a StateStore that saves the snapshot as JSON in a file.
+type FileStore struct {
+	path string
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{
+		path: path,
+	}
+}
+
+func (s *FileStore) Load() (*Snapshot, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// Save writes the snapshot to a temporary file and renames it, so that a crash
+// while saving does not leave a partially written file behind.
+func (s *FileStore) Save(snapshot Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,153 @@
+package ratelimiter
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the rate limiter's state.
+type Stats struct {
+	// Regions holds the application rate limit state of each region.
+	Regions map[string]BucketStats
+
+	// Methods holds the method rate limit state of each method, grouped by region.
+	Methods map[string]map[MethodID]BucketStats
+
+	// Breakers holds the state of each region's circuit breaker, if circuit breakers are enabled.
+	Breakers map[string]BreakerState
+
+	// Keys holds the stats of each key in the key pool, by name. If a key pool is
+	// set, Regions and Methods are empty.
+	Keys map[string]Stats
+
+	// DroppedEvents is the total number of events that were not delivered because the
+	// asynchronous observer fell too far behind. It is only set on the top-level Stats.
+	DroppedEvents int
+}
+
+// BucketStats is the state of the rate limits of a region or a method.
+type BucketStats struct {
+	// Windows are the rate limit windows, with the number of slots currently taken as their Count.
+	Windows []Window
+
+	// BlockedUntil is the time until which no requests are made.
+	BlockedUntil time.Time
+
+	// Queued is the number of requests waiting for a slot.
+	Queued int
+
+	// InFlight is the number of requests that were sent and are awaiting a response.
+	InFlight int
+
+	// Retries is the total number of retried requests.
+	Retries int
+
+	// RateLimited is the total number of 429 responses.
+	RateLimited int
+}
+
+// bucketCounters counts the requests of a bucket. Its fields are updated atomically.
+type bucketCounters struct {
+	key      string
+	region   string
+	methodID MethodID
+
+	queued      int64
+	inFlight    int64
+	retries     int64
+	rateLimited int64
+}
+
+// counters returns the counters of the region and method buckets of a request, creating them if needed.
+func (rl *RateLimiter) counters(keyName, region string, methodID MethodID) (*bucketCounters, *bucketCounters) {
+	rl.countersMutex.Lock()
+	defer rl.countersMutex.Unlock()
+
+	bucket := regionBucket(keyName, region)
+	regionCounters, ok := rl.bucketCounters[bucket]
+	if !ok {
+		regionCounters = &bucketCounters{key: keyName, region: region}
+		rl.bucketCounters[bucket] = regionCounters
+	}
+
+	bucket = methodBucket(keyName, region, methodID)
+	methodCounters, ok := rl.bucketCounters[bucket]
+	if !ok {
+		methodCounters = &bucketCounters{key: keyName, region: region, methodID: methodID}
+		rl.bucketCounters[bucket] = methodCounters
+	}
+
+	return regionCounters, methodCounters
+}
+
+func addCount(delta int64, counts ...*int64) {
+	for _, count := range counts {
+		atomic.AddInt64(count, delta)
+	}
+}
+
+func newStats() Stats {
+	return Stats{
+		Regions: make(map[string]BucketStats),
+		Methods: make(map[string]map[MethodID]BucketStats),
+	}
+}
+
+// Stats returns a snapshot of the state of every region and method that has been requested.
+func (rl *RateLimiter) Stats() (Stats, error) {
+	stats := newStats()
+	stats.Keys = make(map[string]Stats)
+	stats.Breakers = make(map[string]BreakerState)
+	stats.DroppedEvents = int(atomic.LoadInt64(&rl.droppedEvents))
+
+	rl.breakersMutex.Lock()
+	for region, b := range rl.breakers {
+		stats.Breakers[region] = b.currentState()
+	}
+	rl.breakersMutex.Unlock()
+
+	rl.countersMutex.Lock()
+	buckets := make(map[string]*bucketCounters, len(rl.bucketCounters))
+	for bucket, counters := range rl.bucketCounters {
+		buckets[bucket] = counters
+	}
+	rl.countersMutex.Unlock()
+
+	for bucket, counters := range buckets {
+		state, err := rl.backend.State(bucket)
+		if err != nil {
+			return stats, err
+		}
+
+		bucketStats := BucketStats{
+			Windows:      state.Windows,
+			BlockedUntil: state.BlockedUntil,
+			Queued:       int(atomic.LoadInt64(&counters.queued)),
+			InFlight:     int(atomic.LoadInt64(&counters.inFlight)),
+			Retries:      int(atomic.LoadInt64(&counters.retries)),
+			RateLimited:  int(atomic.LoadInt64(&counters.rateLimited)),
+		}
+
+		keyStats := stats
+		if counters.key != "" {
+			if _, ok := stats.Keys[counters.key]; !ok {
+				stats.Keys[counters.key] = newStats()
+			}
+
+			keyStats = stats.Keys[counters.key]
+		}
+
+		if counters.methodID == "" {
+			keyStats.Regions[counters.region] = bucketStats
+			continue
+		}
+
+		if keyStats.Methods[counters.region] == nil {
+			keyStats.Methods[counters.region] = make(map[MethodID]BucketStats)
+		}
+
+		keyStats.Methods[counters.region][counters.methodID] = bucketStats
+	}
+
+	return stats, nil
+}
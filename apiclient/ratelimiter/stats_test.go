@@ -0,0 +1,164 @@
+package ratelimiter
+
+import (
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stats returns the stats of the rate limiter, failing if they cannot be read.
+func stats(t *testing.T, rl *RateLimiter) Stats {
+	t.Helper()
+
+	stats, err := rl.Stats()
+	if err != nil {
+		t.Fatalf("Stats() = %v", err)
+	}
+
+	return stats
+}
+
+// windowLimits returns the limit and count of each window, without their reset times.
+func windowLimits(windows []Window) []Window {
+	limits := make([]Window, 0, len(windows))
+	for _, window := range windows {
+		limits = append(limits, Window{Limit: window.Limit, Count: window.Count, Duration: window.Duration})
+	}
+
+	return limits
+}
+
+func TestStatsWindowsAndRateLimited(t *testing.T) {
+	var calls int32
+	rl, server, fake := newFakeClockRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "5")
+			w.Header().Set("X-Rate-Limit-Type", "method")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.Header().Set("X-App-Rate-Limit", "20:1,100:120")
+		w.Header().Set("X-App-Rate-Limit-Count", "1:1,3:120")
+		w.Header().Set("X-Method-Rate-Limit", "50:10")
+		w.Header().Set("X-Method-Rate-Limit-Count", "4:10")
+		w.Write([]byte("{}"))
+	})
+
+	responses := submit(t, rl, server)
+	waitForTimer(t, fake, responses)
+
+	// The 429 is counted on the region and the method, and the method is blocked until Retry-After passes
+	s := stats(t, rl)
+	region, method := s.Regions["NA1"], s.Methods["NA1"][GetMatch]
+	if region.RateLimited != 1 || method.RateLimited != 1 {
+		t.Errorf("RateLimited = %d for the region and %d for the method, want 1", region.RateLimited, method.RateLimited)
+	}
+
+	if !method.BlockedUntil.Equal(fake.Now().Add(5*time.Second)) || !region.BlockedUntil.IsZero() {
+		t.Errorf("method blocked until %v and region until %v, want the method blocked for 5 seconds", method.BlockedUntil, region.BlockedUntil)
+	}
+
+	fake.Advance(5 * time.Second)
+	receive(t, responses)
+
+	// The windows are those learned from the headers, with one slot kept spare
+	s = stats(t, rl)
+	region, method = s.Regions["NA1"], s.Methods["NA1"][GetMatch]
+
+	wantRegion := []Window{{Limit: 19, Count: 1, Duration: time.Second}, {Limit: 99, Count: 3, Duration: 2 * time.Minute}}
+	if got := windowLimits(region.Windows); !reflect.DeepEqual(got, wantRegion) {
+		t.Errorf("region windows = %+v, want %+v", got, wantRegion)
+	}
+
+	wantMethod := []Window{{Limit: 49, Count: 4, Duration: 10 * time.Second}}
+	if got := windowLimits(method.Windows); !reflect.DeepEqual(got, wantMethod) {
+		t.Errorf("method windows = %+v, want %+v", got, wantMethod)
+	}
+
+	if region.Retries != 1 || method.Retries != 1 || method.RateLimited != 1 {
+		t.Errorf("Retries = %d for the region and %d for the method, RateLimited = %d, want 1, 1 and 1", region.Retries, method.Retries, method.RateLimited)
+	}
+
+	if region.Queued != 0 || region.InFlight != 0 || method.Queued != 0 || method.InFlight != 0 {
+		t.Errorf("region stats = %+v, method stats = %+v, want no requests queued or in flight", region, method)
+	}
+}
+
+func TestStatsQueuedAndInFlight(t *testing.T) {
+	received := make(chan struct{}, 10)
+	release := make(chan struct{})
+	rl, server, fake := newFakeClockRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+
+		w.Write([]byte("{}"))
+	})
+
+	// The initial method limit is 5 requests, so the 6th waits for a slot
+	var responses []<-chan *APIResponse
+	for i := 0; i < 5; i++ {
+		responses = append(responses, submit(t, rl, server))
+		<-received
+	}
+
+	queued := submit(t, rl, server)
+	waitForTimer(t, fake, queued)
+
+	s := stats(t, rl)
+	for name, bucket := range map[string]BucketStats{"region": s.Regions["NA1"], "method": s.Methods["NA1"][GetMatch]} {
+		if bucket.InFlight != 5 || bucket.Queued != 1 {
+			t.Errorf("%s stats = %+v, want 5 requests in flight and 1 queued", name, bucket)
+		}
+	}
+
+	close(release)
+	for _, res := range responses {
+		receive(t, res)
+	}
+
+	fake.Advance(10 * time.Second)
+	receive(t, queued)
+
+	s = stats(t, rl)
+	for name, bucket := range map[string]BucketStats{"region": s.Regions["NA1"], "method": s.Methods["NA1"][GetMatch]} {
+		if bucket.InFlight != 0 || bucket.Queued != 0 || bucket.Retries != 0 || bucket.RateLimited != 0 {
+			t.Errorf("%s stats = %+v, want no requests queued or in flight", name, bucket)
+		}
+	}
+}
+
+func TestStatsGroupsKeys(t *testing.T) {
+	rl, server := newTestRateLimiter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}, func(rl *RateLimiter) {
+		rl.SetAPIKeys([]APIKey{{Name: "a", Key: "RGAPI-a"}, {Name: "b", Key: "RGAPI-b"}})
+	})
+
+	res := send(t, rl, &APIRequest{Region: "NA1", MethodID: GetMatch, URL: server.URL, Key: "b"})
+	if res.Err != nil {
+		t.Fatalf("response error = %v", res.Err)
+	}
+
+	res.Response.Body.Close()
+
+	// With a key pool, the buckets are only reported under their key
+	s := stats(t, rl)
+	if len(s.Regions) != 0 || len(s.Methods) != 0 {
+		t.Errorf("Stats() = %+v, want no buckets outside the keys", s)
+	}
+
+	if _, ok := s.Keys["a"]; ok {
+		t.Errorf("Stats() has the stats of key a, which was not used")
+	}
+
+	if windows := s.Keys["b"].Methods["NA1"][GetMatch].Windows; len(windows) != 1 || windows[0].Count != 1 {
+		t.Errorf("method windows of key b = %+v, want 1 slot taken", windows)
+	}
+}
@@ -0,0 +1,314 @@
+// Package recorder records the Riot API's responses to fixture files and replays them,
+// so that code using the client can be tested without network access or an API key.
+//
+//	rec := recorder.New("testdata/fixtures", recorder.ModeReplay, nil)
+//	client := apiclient.New("", apiclient.WithHTTPClient(&http.Client{Transport: rec}))
+package recorder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode is whether a Recorder records or replays responses.
+type Mode int
+
+const (
+	// ModeReplay serves the recorded responses without sending any request.
+	ModeReplay Mode = iota
+
+	// ModeRecord sends the requests and records their responses, replacing the fixtures
+	// recorded before for the same requests.
+	ModeRecord
+)
+
+// redacted replaces the API key in recorded requests.
+const redacted = "REDACTED"
+
+// Recorder is an http.RoundTripper that records responses to fixture files, or replays them.
+//
+// Each request is recorded in a JSON file named after its URL, with the responses to it in
+// the order they were received. When replaying, the responses to a request are served in
+// the same order, and the last one is served again for any later request, so that retries
+// and rate limit headers replay deterministically. Requests without a fixture get a 404
+// response whose message names the missing fixture, since errors would be retried.
+type Recorder struct {
+	dir       string
+	mode      Mode
+	transport http.RoundTripper
+
+	mutex    sync.Mutex
+	fixtures map[string]*Fixture // nil for the files that do not exist
+	served   map[string]int
+	missing  []string
+}
+
+// Fixture is the content of a fixture file.
+type Fixture struct {
+	Method       string        `json:"method"`
+	URL          string        `json:"url"`
+	Header       http.Header   `json:"header,omitempty"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a response recorded for a request.
+type Interaction struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+
+	// Body is the response's body if it is JSON, and BodyText otherwise.
+	Body     json.RawMessage `json:"body,omitempty"`
+	BodyText string          `json:"body_text,omitempty"`
+}
+
+// New returns a Recorder that stores its fixtures in dir. In ModeRecord, requests are sent
+// with transport, or http.DefaultTransport if it is nil.
+func New(dir string, mode Mode, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Recorder{
+		dir:       dir,
+		mode:      mode,
+		transport: transport,
+		fixtures:  make(map[string]*Fixture),
+		served:    make(map[string]int),
+	}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == ModeRecord {
+		return r.record(req)
+	}
+
+	return r.replay(req)
+}
+
+// Missing returns the URLs of the requests that had no fixture to replay, with their API key redacted.
+func (r *Recorder) Missing() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]string(nil), r.missing...)
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	interaction := Interaction{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+	}
+
+	// The body is reformatted in the fixture, so its length is not kept
+	interaction.Header.Del("Content-Length")
+
+	if json.Valid(body) {
+		interaction.Body = body
+	} else {
+		interaction.BodyText = string(body)
+	}
+
+	URL := redactURL(req.URL)
+	name := fileName(req.Method, URL)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Fixtures recorded in an earlier run are replaced, rather than appended to
+	f := r.fixtures[name]
+	if f == nil {
+		f = &Fixture{
+			Method: req.Method,
+			URL:    URL,
+			Header: redactHeader(req.Header),
+		}
+
+		r.fixtures[name] = f
+	}
+
+	f.Interactions = append(f.Interactions, interaction)
+
+	if err := r.write(name, f); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	URL := redactURL(req.URL)
+	name := fileName(req.Method, URL)
+
+	r.mutex.Lock()
+	f, err := r.load(name)
+	if err != nil {
+		r.mutex.Unlock()
+		return nil, err
+	}
+
+	if f == nil || len(f.Interactions) == 0 {
+		r.missing = append(r.missing, URL)
+		r.mutex.Unlock()
+
+		return missingResponse(req, name), nil
+	}
+
+	i := r.served[name]
+	if i >= len(f.Interactions) {
+		i = len(f.Interactions) - 1
+	}
+
+	r.served[name]++
+	r.mutex.Unlock()
+
+	interaction := f.Interactions[i]
+	body := []byte(interaction.Body)
+	if interaction.Body == nil {
+		body = []byte(interaction.BodyText)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+		StatusCode:    interaction.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// load returns the fixture stored in the named file, reading it if needed. The caller must hold r.mutex.
+func (r *Recorder) load(name string) (*Fixture, error) {
+	if f, ok := r.fixtures[name]; ok {
+		return f, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(r.dir, name))
+	if os.IsNotExist(err) {
+		r.fixtures[name] = nil
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fixture{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("recorder: %s: %w", name, err)
+	}
+
+	r.fixtures[name] = f
+	return f, nil
+}
+
+// write writes a fixture to the named file. The caller must hold r.mutex.
+func (r *Recorder) write(name string, f *Fixture) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(r.dir, name), append(data, '\n'), 0o644)
+}
+
+// missingResponse returns the 404 response served for a request without a fixture.
+func missingResponse(req *http.Request, name string) *http.Response {
+	body, _ := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"message":     "recorder: no fixture " + name + " for " + redactURL(req.URL),
+			"status_code": http.StatusNotFound,
+		},
+	})
+
+	return &http.Response{
+		Status:        "404 Not Found",
+		StatusCode:    http.StatusNotFound,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json;charset=utf-8"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// fileName returns the name of the fixture file of a request: its host and path, made safe
+// for file names, and a hash of its method and URL to tell apart requests that only differ
+// in their query or in characters that were replaced.
+func fileName(method, URL string) string {
+	readable := URL
+	if u, err := url.Parse(URL); err == nil {
+		readable = u.Host + u.Path
+	}
+
+	readable = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+			return r
+		}
+
+		return '_'
+	}, readable)
+
+	if len(readable) > 120 {
+		readable = readable[:120]
+	}
+
+	hash := sha256.Sum256([]byte(method + " " + URL))
+	return readable + "-" + hex.EncodeToString(hash[:4]) + ".json"
+}
+
+// redactURL returns the URL with the api_key query parameter redacted, if it has one.
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	if query.Get("api_key") == "" {
+		return u.String()
+	}
+
+	query.Set("api_key", redacted)
+
+	redactedURL := *u
+	redactedURL.RawQuery = query.Encode()
+	return redactedURL.String()
+}
+
+// redactHeader returns a copy of the request's header with the API key redacted.
+func redactHeader(header http.Header) http.Header {
+	header = header.Clone()
+	if header.Get("X-Riot-Token") != "" {
+		header.Set("X-Riot-Token", redacted)
+	}
+
+	return header
+}
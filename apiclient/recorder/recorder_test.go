@@ -0,0 +1,157 @@
+package recorder_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/recorder"
+)
+
+const apiKey = "RGAPI-secret"
+
+// newUpstream returns a server that responds to the nth request with {"n":n}.
+func newUpstream(t *testing.T) (*httptest.Server, *int32) {
+	var requests int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json;charset=utf-8")
+		io.WriteString(w, `{"n":`+strconv.Itoa(int(n))+`}`)
+	}))
+
+	t.Cleanup(upstream.Close)
+	return upstream, &requests
+}
+
+// get sends a GET request for the URL with the recorder, and returns the response's
+// status code and compacted body.
+func get(t *testing.T, rec *recorder.Recorder, URL string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("X-Riot-Token", apiKey)
+
+	resp, err := (&http.Client{Transport: rec}).Do(req)
+	if err != nil {
+		t.Fatalf("GET %s = %v", URL, err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fixtures are indented, so replayed bodies are compacted before they are compared
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, body); err != nil {
+		t.Fatalf("GET %s = invalid JSON %s", URL, body)
+	}
+
+	return resp.StatusCode, compacted.String()
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	upstream, requests := newUpstream(t)
+	dir := t.TempDir()
+
+	summonerURL := upstream.URL + "/lol/summoner/v4/summoners/by-puuid/puuid"
+	matchURL := upstream.URL + "/lol/match/v5/matches/NA1_1?api_key=" + apiKey
+
+	rec := recorder.New(dir, recorder.ModeRecord, nil)
+	for _, URL := range []string{summonerURL, summonerURL, matchURL} {
+		if status, _ := get(t, rec, URL); status != http.StatusOK {
+			t.Fatalf("GET %s = %d while recording", URL, status)
+		}
+	}
+
+	// Replaying must not send any request upstream
+	upstream.Close()
+	recorded := atomic.LoadInt32(requests)
+
+	rec = recorder.New(dir, recorder.ModeReplay, nil)
+
+	// The responses to a request are replayed in order, and the last one is repeated
+	for i, want := range []string{`{"n":1}`, `{"n":2}`, `{"n":2}`} {
+		if status, body := get(t, rec, summonerURL); status != http.StatusOK || body != want {
+			t.Errorf("replay %d = %d %s, want 200 %s", i, status, body, want)
+		}
+	}
+
+	// The API key is redacted before the fixture is looked up, so any key replays it
+	otherKeyURL := strings.Replace(matchURL, apiKey, "RGAPI-other", 1)
+	if status, body := get(t, rec, otherKeyURL); status != http.StatusOK || body != `{"n":3}` {
+		t.Errorf("replay with another API key = %d %s, want 200 {\"n\":3}", status, body)
+	}
+
+	if n := atomic.LoadInt32(requests); n != recorded {
+		t.Errorf("replaying sent %d requests upstream", n-recorded)
+	}
+
+	if missing := rec.Missing(); len(missing) != 0 {
+		t.Errorf("Missing() = %q, want none", missing)
+	}
+}
+
+func TestRecordRedactsAPIKey(t *testing.T) {
+	upstream, _ := newUpstream(t)
+	dir := t.TempDir()
+
+	rec := recorder.New(dir, recorder.ModeRecord, nil)
+	get(t, rec, upstream.URL+"/lol/match/v5/matches/NA1_1?api_key="+apiKey)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("recorded %d fixtures (%v), want 1", len(files), err)
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(data, []byte(apiKey)) {
+		t.Errorf("fixture contains the API key:\n%s", data)
+	}
+
+	var fixture recorder.Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+
+	if token := fixture.Header.Get("X-Riot-Token"); token != "REDACTED" {
+		t.Errorf("fixture's X-Riot-Token = %q, want REDACTED", token)
+	}
+
+	if !strings.HasSuffix(fixture.URL, "?api_key=REDACTED") {
+		t.Errorf("fixture's URL = %q, want its api_key redacted", fixture.URL)
+	}
+}
+
+func TestReplayMissingFixture(t *testing.T) {
+	rec := recorder.New(t.TempDir(), recorder.ModeReplay, nil)
+
+	URL := "https://na1.api.riotgames.com/lol/summoner/v4/summoners/by-puuid/puuid?api_key=" + apiKey
+	status, body := get(t, rec, URL)
+	if status != http.StatusNotFound || !strings.Contains(body, "recorder: no fixture") {
+		t.Errorf("replay without a fixture = %d %s, want 404", status, body)
+	}
+
+	missing := rec.Missing()
+	if len(missing) != 1 || strings.Contains(missing[0], apiKey) || !strings.Contains(missing[0], "api_key=REDACTED") {
+		t.Errorf("Missing() = %q, want the redacted URL", missing)
+	}
+}
@@ -0,0 +1,76 @@
+package apiclient
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+)
+
+// ResponseInfo describes the response to a call and how long the call took.
+// See Client.WithResponseInfo.
+type ResponseInfo struct {
+	Region   string
+	MethodID ratelimiter.MethodID
+	URL      string
+
+	// Key is the name of the key in the key pool that the call was sent with, if a key pool is set.
+	Key string
+
+	// StatusCode and Header are those of the response, or zero if no response was received.
+	StatusCode int
+	Header     http.Header
+
+	// Date is the response's Date header, and TraceID its X-Riot-Edge-Trace-Id header.
+	Date    time.Time
+	TraceID string
+
+	// AppRateLimit and MethodRateLimit are the application and method rate limits of the
+	// response's headers, with the counts Riot reported as their Count.
+	AppRateLimit    []ratelimiter.Window
+	MethodRateLimit []ratelimiter.Window
+
+	// Retries is the number of times the call was retried.
+	Retries int
+
+	// QueueWait is the total time the call waited for rate limit slots, and Latency is how long
+	// its last HTTP request took. Duration is the time from the call to its response, which also
+	// includes the delays before retries.
+	QueueWait time.Duration
+	Latency   time.Duration
+	Duration  time.Duration
+
+	// Coalesced is set if the call shared the response of an identical call that was already
+	// in flight. The other fields then describe that call.
+	Coalesced bool
+
+	// Cached is set if the call was served from the cache. Only Region, MethodID and URL are then set.
+	Cached bool
+}
+
+// newResponseInfo returns the metadata of a request's response, which is nil if none was received.
+func newResponseInfo(req *ratelimiter.APIRequest, response *Response, duration time.Duration) ResponseInfo {
+	info := ResponseInfo{
+		Region:    req.Region,
+		MethodID:  req.MethodID,
+		URL:       req.URL,
+		Key:       req.KeyName,
+		Retries:   req.Retries,
+		QueueWait: req.QueueWait,
+		Latency:   req.Latency,
+		Duration:  duration,
+	}
+
+	if response == nil {
+		return info
+	}
+
+	info.StatusCode = response.StatusCode
+	info.Header = response.Header
+	info.Date, _ = http.ParseTime(response.Header.Get("Date"))
+	info.TraceID = response.Header.Get("X-Riot-Edge-Trace-Id")
+	info.AppRateLimit = ratelimiter.ParseRateLimitHeaders(response.Header.Get("X-App-Rate-Limit"), response.Header.Get("X-App-Rate-Limit-Count"))
+	info.MethodRateLimit = ratelimiter.ParseRateLimitHeaders(response.Header.Get("X-Method-Rate-Limit"), response.Header.Get("X-Method-Rate-Limit-Count"))
+
+	return info
+}
@@ -0,0 +1,100 @@
+package apiclient_test
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/apiclienttest"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/cache"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+	"github.com/Kinveil/Riot-API-Golang/constants/region"
+)
+
+func TestResponseInfo(t *testing.T) {
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	server.Handle(ratelimiter.GetSummonerByPuuid, func(apiclienttest.Request) apiclienttest.Response {
+		return apiclienttest.Response{
+			Header: http.Header{"X-Riot-Edge-Trace-Id": {"trace"}},
+			Body:   `{"name": "Faker"}`,
+		}
+	})
+
+	var info apiclient.ResponseInfo
+	start := time.Now()
+	if _, err := server.NewClient().WithResponseInfo(&info).GetSummonerByPuuid(region.KR, "puuid"); err != nil {
+		t.Fatalf("GetSummonerByPuuid() = %v", err)
+	}
+
+	if info.Region != "KR" || info.MethodID != ratelimiter.GetSummonerByPuuid || !strings.HasSuffix(info.URL, "/lol/summoner/v4/summoners/by-puuid/puuid") {
+		t.Errorf("ResponseInfo = %+v, want the region, method and URL of the call", info)
+	}
+
+	if info.StatusCode != http.StatusOK || info.TraceID != "trace" || info.Header.Get("Content-Type") == "" {
+		t.Errorf("ResponseInfo = %+v, want the status code and headers of the response", info)
+	}
+
+	// The server's Date header has a precision of a second
+	if info.Date.Before(start.Truncate(time.Second)) || info.Date.After(time.Now()) {
+		t.Errorf("ResponseInfo Date = %v, want the time of the response", info.Date)
+	}
+
+	wantApp := []ratelimiter.Window{{Limit: 500, Count: 1, Duration: 10 * time.Second}, {Limit: 30000, Count: 1, Duration: 10 * time.Minute}}
+	wantMethod := []ratelimiter.Window{{Limit: 2000, Count: 1, Duration: 10 * time.Second}}
+	if !reflect.DeepEqual(info.AppRateLimit, wantApp) || !reflect.DeepEqual(info.MethodRateLimit, wantMethod) {
+		t.Errorf("ResponseInfo rate limits = %+v and %+v, want %+v and %+v", info.AppRateLimit, info.MethodRateLimit, wantApp, wantMethod)
+	}
+
+	if info.Retries != 0 || info.Latency <= 0 || info.Duration < info.Latency || info.Cached || info.Coalesced {
+		t.Errorf("ResponseInfo = %+v, want a call sent once", info)
+	}
+}
+
+func TestResponseInfoOfCachedCall(t *testing.T) {
+	_, client := newCachingServer(t, cache.NewLRU(10))
+
+	summonerName(t, client)
+
+	var info apiclient.ResponseInfo
+	if _, err := client.WithResponseInfo(&info).GetSummonerByPuuid(region.KR, "puuid"); err != nil {
+		t.Fatalf("GetSummonerByPuuid() = %v", err)
+	}
+
+	// Only the call itself is described, since no response was received
+	want := apiclient.ResponseInfo{Region: "KR", MethodID: ratelimiter.GetSummonerByPuuid, URL: info.URL, Cached: true}
+	if !reflect.DeepEqual(info, want) || !strings.HasSuffix(info.URL, "/puuid") {
+		t.Errorf("ResponseInfo = %+v, want %+v", info, want)
+	}
+}
+
+func TestResponseInfoOfRetriedCall(t *testing.T) {
+	server := apiclienttest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(ratelimiter.GetSummonerByPuuid, apiclient.Summoner{Name: "Faker"})
+	server.Inject(apiclienttest.Fault{MethodID: ratelimiter.GetSummonerByPuuid, Count: 2, StatusCode: http.StatusInternalServerError})
+
+	delay := 20 * time.Millisecond
+	retries := apiclient.WithRetryPolicy(ratelimiter.RetryPolicyFunc(func(attempt ratelimiter.RetryAttempt) (bool, time.Duration) {
+		return attempt.StatusCode == http.StatusInternalServerError, delay
+	}))
+
+	var info apiclient.ResponseInfo
+	if _, err := server.NewClient(retries).WithResponseInfo(&info).GetSummonerByPuuid(region.KR, "puuid"); err != nil {
+		t.Fatalf("GetSummonerByPuuid() = %v", err)
+	}
+
+	// The response is the last one, and the duration includes the delays before the retries
+	if info.Retries != 2 || info.StatusCode != http.StatusOK || len(info.MethodRateLimit) != 1 {
+		t.Errorf("ResponseInfo = %+v, want a successful response after 2 retries", info)
+	}
+
+	if info.Duration < 2*delay || info.Latency >= info.Duration {
+		t.Errorf("ResponseInfo Duration = %v, Latency = %v, want a duration of at least %v, longer than the latency", info.Duration, info.Latency, 2*delay)
+	}
+}
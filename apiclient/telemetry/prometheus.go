@@ -0,0 +1,178 @@
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the histogram buckets, in seconds, used by NewPrometheus.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// Prometheus is a Metrics implementation that serves its measurements in the
+// Prometheus text exposition format. It implements http.Handler, so it can be
+// mounted on a /metrics endpoint.
+type Prometheus struct {
+	namespace string
+	buckets   []float64
+
+	mutex       sync.Mutex
+	latency     map[string]*histogram
+	queueWait   map[string]*histogram
+	requests    map[string]uint64
+	rateLimited map[string]uint64
+}
+
+type histogram struct {
+	labels []string
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewPrometheus returns a Prometheus whose metric names are prefixed with namespace.
+// If namespace is empty, "riot_api" is used.
+func NewPrometheus(namespace string) *Prometheus {
+	if namespace == "" {
+		namespace = "riot_api"
+	}
+
+	return &Prometheus{
+		namespace:   namespace,
+		buckets:     DefaultBuckets,
+		latency:     make(map[string]*histogram),
+		queueWait:   make(map[string]*histogram),
+		requests:    make(map[string]uint64),
+		rateLimited: make(map[string]uint64),
+	}
+}
+
+func labelKey(values ...string) string {
+	return strings.Join(values, "\xff")
+}
+
+func (p *Prometheus) observe(histograms map[string]*histogram, d time.Duration, labels ...string) {
+	key := labelKey(labels...)
+	h, ok := histograms[key]
+	if !ok {
+		h = &histogram{
+			labels: labels,
+			counts: make([]uint64, len(p.buckets)),
+		}
+
+		histograms[key] = h
+	}
+
+	seconds := d.Seconds()
+	for i, bound := range p.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+
+	h.sum += seconds
+	h.count++
+}
+
+func (p *Prometheus) ObserveRequest(region, method string, statusCode int, latency time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.observe(p.latency, latency, region, method)
+	p.requests[labelKey(region, method, strconv.Itoa(statusCode))]++
+}
+
+func (p *Prometheus) ObserveQueueWait(region, method string, wait time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.observe(p.queueWait, wait, region, method)
+}
+
+func (p *Prometheus) IncRateLimited(region, method, limitType string) {
+	if limitType == "" {
+		limitType = "unknown"
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.rateLimited[labelKey(region, method, limitType)]++
+}
+
+// ServeHTTP writes every metric in the Prometheus text exposition format.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+// WriteTo writes every metric in the Prometheus text exposition format.
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var sb strings.Builder
+
+	p.writeHistograms(&sb, "request_duration_seconds", "Latency of HTTP requests sent to the Riot API.", p.latency)
+	p.writeCounters(&sb, "requests_total", "HTTP requests sent to the Riot API by status code.", p.requests, "region", "method", "status")
+	p.writeHistograms(&sb, "queue_wait_seconds", "Time requests spent waiting on the rate limiter.", p.queueWait)
+	p.writeCounters(&sb, "rate_limited_total", "429 responses by rate limit type.", p.rateLimited, "region", "method", "type")
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+func (p *Prometheus) writeHistograms(sb *strings.Builder, name, help string, histograms map[string]*histogram) {
+	name = p.namespace + "_" + name
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+
+	for _, key := range sortedKeys(histograms) {
+		h := histograms[key]
+		labels := formatLabels([]string{"region", "method"}, h.labels)
+
+		for i, bound := range p.buckets {
+			fmt.Fprintf(sb, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+		}
+
+		fmt.Fprintf(sb, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+		fmt.Fprintf(sb, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(sb, "%s_count{%s} %d\n", name, labels, h.count)
+	}
+}
+
+func (p *Prometheus) writeCounters(sb *strings.Builder, name, help string, counters map[string]uint64, labelNames ...string) {
+	name = p.namespace + "_" + name
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+
+	for _, key := range sortedKeys(counters) {
+		fmt.Fprintf(sb, "%s{%s} %d\n", name, formatLabels(labelNames, strings.Split(key, "\xff")), counters[key])
+	}
+}
+
+// labelValueEscaper escapes a label value. The exposition format only has escapes for
+// backslashes, double quotes and line feeds, so other characters are written as they are.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string) string {
+	labels := make([]string, len(names))
+	for i, name := range names {
+		labels[i] = name + "=\"" + labelValueEscaper.Replace(values[i]) + "\""
+	}
+
+	return strings.Join(labels, ",")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,52 @@
+package telemetry
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/prometheus.txt with the exporter's output")
+
+func TestPrometheusExposition(t *testing.T) {
+	p := NewPrometheus("")
+	p.buckets = []float64{0.1, 1}
+
+	p.ObserveRequest("NA1", "GetMatch", 200, 50*time.Millisecond)
+	p.ObserveRequest("NA1", "GetMatch", 200, 500*time.Millisecond)
+	p.ObserveRequest("NA1", "GetMatch", 503, 2*time.Second)
+	p.ObserveRequest("KR", "GetSummonerByPuuid", 0, 0)
+	p.ObserveQueueWait("NA1", "GetMatch", 250*time.Millisecond)
+	p.IncRateLimited("NA1", "GetMatch", "method")
+	p.IncRateLimited("NA1", "GetMatch", "")
+
+	// Only backslashes, double quotes and line feeds are escaped in label values
+	p.IncRateLimited(`a"b\c`+"\n", "é\t", "service")
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want the text exposition format", contentType)
+	}
+
+	got := recorder.Body.Bytes()
+	if *update {
+		if err := os.WriteFile("testdata/prometheus.txt", got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile("testdata/prometheus.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("exposition =\n%s\nwant testdata/prometheus.txt:\n%s", got, want)
+	}
+}
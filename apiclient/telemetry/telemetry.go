@@ -0,0 +1,55 @@
+// Package telemetry defines the metrics and tracing hooks used by the API client.
+//
+// The interfaces are kept small so they can be adapted to any metrics or tracing library.
+// A Prometheus implementation is provided by NewPrometheus, and Tracer mirrors the subset
+// of OpenTelemetry's trace.Tracer that the client uses. No OpenTelemetry adapter is included,
+// so that the module does not depend on OpenTelemetry; the README shows how to write one.
+package telemetry
+
+import (
+	"context"
+	"time"
+)
+
+// Metrics records measurements of the requests made to the Riot API.
+// Region is the region or continent of the request and method is its MethodID.
+type Metrics interface {
+	// ObserveRequest records an HTTP request sent to Riot. The statusCode is 0 if no response was received.
+	ObserveRequest(region, method string, statusCode int, latency time.Duration)
+
+	// ObserveQueueWait records how long a request waited on the rate limiter before it was sent.
+	ObserveQueueWait(region, method string, wait time.Duration)
+
+	// IncRateLimited counts a 429 response by its X-Rate-Limit-Type header, e.g. "application", "method" or "service".
+	IncRateLimited(region, method, limitType string)
+}
+
+// Tracer starts spans around API calls, rate limiter waits and HTTP requests.
+type Tracer interface {
+	Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span)
+}
+
+// Span is a single traced operation started by a Tracer.
+type Span interface {
+	SetAttributes(attributes ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a key-value pair attached to a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span names and attribute keys used by the client.
+const (
+	SpanCall     = "riot.call"
+	SpanWait     = "riot.ratelimiter.wait"
+	SpanRequest  = "riot.http.request"
+	AttrRegion   = "riot.region"
+	AttrMethodID = "riot.method_id"
+	AttrURL      = "http.url"
+	AttrStatus   = "http.status_code"
+	AttrRetries  = "riot.retries"
+)
@@ -0,0 +1,269 @@
+// Package redis is a minimal client for servers that speak the Redis protocol (RESP).
+// It only supports what this module needs: sending commands and reading their replies.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Error is an error reply sent by the server.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// ErrClosed is returned when a command is sent on a closed Client.
+var ErrClosed = errors.New("redis: client is closed")
+
+type Options struct {
+	// Addr is the host:port address of the server.
+	Addr string
+
+	// Password is sent with AUTH when a connection is opened, if set.
+	Password string
+
+	// DB is selected with SELECT when a connection is opened, if set.
+	DB int
+
+	// PoolSize is the maximum number of idle connections kept open. Defaults to 10.
+	PoolSize int
+
+	// DialTimeout is the timeout for opening a connection. Defaults to 5 seconds.
+	DialTimeout time.Duration
+}
+
+// Client is a pool of connections to a Redis server. It is safe for concurrent use.
+type Client struct {
+	opts Options
+	idle chan *conn
+	done chan struct{}
+}
+
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+}
+
+func NewClient(opts Options) *Client {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 10
+	}
+
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+
+	return &Client{
+		opts: opts,
+		idle: make(chan *conn, opts.PoolSize),
+		done: make(chan struct{}),
+	}
+}
+
+// Do sends a command and returns its reply. Replies are returned as string, int64,
+// []interface{} or nil, and error replies are returned as an Error.
+func (c *Client) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		cn.netConn.SetDeadline(deadline)
+	} else {
+		cn.netConn.SetDeadline(time.Time{})
+	}
+
+	reply, err := cn.do(args...)
+	if err != nil {
+		var replyErr Error
+		if !errors.As(err, &replyErr) {
+			// The connection is in an unknown state, so it cannot be reused
+			cn.netConn.Close()
+			return nil, err
+		}
+	}
+
+	c.put(cn)
+	return reply, err
+}
+
+// Close closes all idle connections. Connections in use are closed when they are returned.
+func (c *Client) Close() error {
+	select {
+	case <-c.done:
+		return nil
+	default:
+		close(c.done)
+	}
+
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.netConn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case <-c.done:
+		return nil, ErrClosed
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: c.opts.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cn := &conn{
+		netConn: netConn,
+		reader:  bufio.NewReader(netConn),
+		writer:  bufio.NewWriter(netConn),
+	}
+
+	if c.opts.Password != "" {
+		if _, err := cn.do("AUTH", c.opts.Password); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+
+	if c.opts.DB != 0 {
+		if _, err := cn.do("SELECT", c.opts.DB); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+
+	return cn, nil
+}
+
+func (c *Client) put(cn *conn) {
+	select {
+	case <-c.done:
+		cn.netConn.Close()
+		return
+	default:
+	}
+
+	select {
+	case c.idle <- cn:
+	default:
+		cn.netConn.Close()
+	}
+}
+
+func (cn *conn) do(args ...interface{}) (interface{}, error) {
+	fmt.Fprintf(cn.writer, "*%d\r\n", len(args))
+
+	for _, arg := range args {
+		var s string
+		switch arg := arg.(type) {
+		case []byte:
+			s = string(arg)
+		default:
+			s = fmt.Sprint(arg)
+		}
+
+		fmt.Fprintf(cn.writer, "$%d\r\n%s\r\n", len(s), s)
+	}
+
+	if err := cn.writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	return cn.read()
+}
+
+func (cn *conn) read() (interface{}, error) {
+	line, err := cn.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		if n < 0 {
+			return nil, nil
+		}
+
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(cn.reader, buf); err != nil {
+			return nil, err
+		}
+
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		if n < 0 {
+			return nil, nil
+		}
+
+		values := make([]interface{}, n)
+		for i := range values {
+			value, err := cn.read()
+			if err != nil {
+				var replyErr Error
+				if !errors.As(err, &replyErr) {
+					return nil, err
+				}
+
+				value = replyErr
+			}
+
+			values[i] = value
+		}
+
+		return values, nil
+	}
+
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+func (cn *conn) readLine() (string, error) {
+	line, err := cn.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("redis: malformed reply %q", line)
+	}
+
+	return line[:len(line)-2], nil
+}
@@ -0,0 +1,292 @@
+package redistest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter"
+	"github.com/Kinveil/Riot-API-Golang/apiclient/ratelimiter/clock"
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestRedisBackend returns a RedisBackend connected to an in-process Redis server, which
+// runs the backend's Lua scripts.
+func newTestRedisBackend(t *testing.T) (*ratelimiter.RedisBackend, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	backend := ratelimiter.NewRedisBackend(ratelimiter.RedisOptions{Addr: server.Addr(), PollInterval: 10 * time.Millisecond})
+	t.Cleanup(func() {
+		backend.Close()
+	})
+
+	return backend, server
+}
+
+func TestRedisBackendObtain(t *testing.T) {
+	backend, server := newTestRedisBackend(t)
+	initial := []ratelimiter.Window{{Limit: 2, Duration: time.Second}, {Limit: 100, Duration: time.Minute}}
+
+	for i := 0; i < 2; i++ {
+		if err := backend.Obtain(context.Background(), "NA1", initial); err != nil {
+			t.Fatalf("Obtain() = %v", err)
+		}
+	}
+
+	wait, err := backend.TryObtain("NA1", initial)
+	if err != nil || wait <= 0 || wait > time.Second {
+		t.Fatalf("TryObtain() on a full bucket = %v, %v, want a wait of up to 1s", wait, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := backend.Obtain(ctx, "NA1", initial); err != context.DeadlineExceeded {
+		t.Fatalf("Obtain() on a full bucket = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The 1 second window resets, but the 1 minute window keeps counting
+	server.FastForward(time.Second)
+
+	if wait, err := backend.TryObtain("NA1", initial); err != nil || wait != 0 {
+		t.Fatalf("TryObtain() after the window reset = %v, %v, want 0", wait, err)
+	}
+
+	if count, _ := server.Get("riot-ratelimit:NA1:count:60000"); count != "3" {
+		t.Errorf("1 minute window count = %q, want 3", count)
+	}
+
+	// Buckets are independent
+	if wait, err := backend.TryObtain("EUW1", initial); err != nil || wait != 0 {
+		t.Errorf("TryObtain() on another bucket = %v, %v, want 0", wait, err)
+	}
+}
+
+func TestRedisBackendUpdate(t *testing.T) {
+	backend, _ := newTestRedisBackend(t)
+
+	if err := backend.Obtain(context.Background(), "NA1", []ratelimiter.Window{{Limit: 20, Duration: time.Second}}); err != nil {
+		t.Fatalf("Obtain() = %v", err)
+	}
+
+	// Riot reports other windows, and more requests than this process made
+	windows := []ratelimiter.Window{
+		{Limit: 500, Count: 7, Duration: 10 * time.Second},
+		{Limit: 30000, Count: 40, Duration: 10 * time.Minute},
+	}
+
+	if err := backend.Update("NA1", windows); err != nil {
+		t.Fatalf("Update() = %v", err)
+	}
+
+	state, err := backend.State("NA1")
+	if err != nil {
+		t.Fatalf("State() = %v", err)
+	}
+
+	if len(state.Windows) != 2 {
+		t.Fatalf("State().Windows = %+v, want the 2 updated windows", state.Windows)
+	}
+
+	for i, window := range state.Windows {
+		if window.Limit != windows[i].Limit || window.Count != windows[i].Count || window.Duration != windows[i].Duration {
+			t.Errorf("window %d = %+v, want %+v", i, window, windows[i])
+		}
+	}
+
+	// A lower count than the one already recorded does not lower it
+	windows[0].Count = 1
+	if err := backend.Update("NA1", windows); err != nil {
+		t.Fatalf("Update() = %v", err)
+	}
+
+	if state, _ := backend.State("NA1"); state.Windows[0].Count != 7 {
+		t.Errorf("count after a lower update = %d, want 7", state.Windows[0].Count)
+	}
+
+	// Updated limits are used by Obtain, rather than the initial ones
+	if err := backend.Update("NA1", []ratelimiter.Window{{Limit: 1, Count: 1, Duration: 10 * time.Second}}); err != nil {
+		t.Fatalf("Update() = %v", err)
+	}
+
+	if wait, err := backend.TryObtain("NA1", []ratelimiter.Window{{Limit: 20, Duration: time.Second}}); err != nil || wait <= 0 {
+		t.Errorf("TryObtain() after lowering the limit = %v, %v, want a wait", wait, err)
+	}
+}
+
+func TestRedisBackendRelease(t *testing.T) {
+	backend, _ := newTestRedisBackend(t)
+	initial := []ratelimiter.Window{{Limit: 1, Duration: time.Minute}}
+
+	if wait, err := backend.TryObtain("NA1", initial); err != nil || wait != 0 {
+		t.Fatalf("TryObtain() = %v, %v, want 0", wait, err)
+	}
+
+	if wait, err := backend.TryObtain("NA1", initial); err != nil || wait <= 0 {
+		t.Fatalf("TryObtain() on a full bucket = %v, %v, want a wait", wait, err)
+	}
+
+	if err := backend.Release("NA1"); err != nil {
+		t.Fatalf("Release() = %v", err)
+	}
+
+	if wait, err := backend.TryObtain("NA1", initial); err != nil || wait != 0 {
+		t.Fatalf("TryObtain() after Release() = %v, %v, want 0", wait, err)
+	}
+
+	// Releasing more slots than were taken does not make the count negative
+	for i := 0; i < 3; i++ {
+		if err := backend.Release("NA1"); err != nil {
+			t.Fatalf("Release() = %v", err)
+		}
+	}
+
+	if state, _ := backend.State("NA1"); state.Windows[0].Count != 0 {
+		t.Errorf("count after releasing every slot = %d, want 0", state.Windows[0].Count)
+	}
+}
+
+func TestRedisBackendBlock(t *testing.T) {
+	backend, server := newTestRedisBackend(t)
+
+	if until, err := backend.BlockedUntil("NA1"); err != nil || !until.IsZero() {
+		t.Fatalf("BlockedUntil() before Block() = %v, %v, want the zero time", until, err)
+	}
+
+	until := time.Now().Add(time.Minute)
+	if err := backend.Block("NA1", until); err != nil {
+		t.Fatalf("Block() = %v", err)
+	}
+
+	blockedUntil, err := backend.BlockedUntil("NA1")
+	if err != nil || blockedUntil.UnixMilli() != until.UnixMilli() {
+		t.Fatalf("BlockedUntil() = %v, %v, want %v", blockedUntil, err, until)
+	}
+
+	if until, err := backend.BlockedUntil("EUW1"); err != nil || !until.IsZero() {
+		t.Errorf("BlockedUntil() of another bucket = %v, %v, want the zero time", until, err)
+	}
+
+	// Blocking until a time in the past does nothing
+	if err := backend.Block("EUW1", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Block() = %v", err)
+	}
+
+	if server.Exists("riot-ratelimit:EUW1:blocked") {
+		t.Error("Block() with a past time stored a block")
+	}
+
+	// The block expires with its time
+	server.FastForward(time.Minute)
+
+	if until, err := backend.BlockedUntil("NA1"); err != nil || !until.IsZero() {
+		t.Errorf("BlockedUntil() after the block expired = %v, %v, want the zero time", until, err)
+	}
+}
+
+func TestRedisBackendState(t *testing.T) {
+	backend, _ := newTestRedisBackend(t)
+
+	if state, err := backend.State("NA1"); err != nil || len(state.Windows) != 0 || !state.BlockedUntil.IsZero() {
+		t.Fatalf("State() of an unused bucket = %+v, %v, want an empty state", state, err)
+	}
+
+	initial := []ratelimiter.Window{{Limit: 100, Duration: 2 * time.Minute}, {Limit: 20, Duration: time.Second}}
+	for i := 0; i < 3; i++ {
+		if err := backend.Obtain(context.Background(), "NA1", initial); err != nil {
+			t.Fatalf("Obtain() = %v", err)
+		}
+	}
+
+	until := time.Now().Add(time.Minute)
+	if err := backend.Block("NA1", until); err != nil {
+		t.Fatalf("Block() = %v", err)
+	}
+
+	state, err := backend.State("NA1")
+	if err != nil {
+		t.Fatalf("State() = %v", err)
+	}
+
+	if state.BlockedUntil.UnixMilli() != until.UnixMilli() {
+		t.Errorf("BlockedUntil = %v, want %v", state.BlockedUntil, until)
+	}
+
+	// Windows are sorted by duration
+	expect := []ratelimiter.Window{{Limit: 20, Count: 3, Duration: time.Second}, {Limit: 100, Count: 3, Duration: 2 * time.Minute}}
+	if len(state.Windows) != len(expect) {
+		t.Fatalf("Windows = %+v, want %+v", state.Windows, expect)
+	}
+
+	for i, window := range state.Windows {
+		if window.Limit != expect[i].Limit || window.Count != expect[i].Count || window.Duration != expect[i].Duration {
+			t.Errorf("window %d = %+v, want %+v", i, window, expect[i])
+		}
+
+		if resetIn := time.Until(window.ResetAt); resetIn <= 0 || resetIn > window.Duration {
+			t.Errorf("window %d resets in %v, want within %v", i, resetIn, window.Duration)
+		}
+	}
+}
+
+func TestRedisBackendUpdateDeletesOldWindows(t *testing.T) {
+	backend, server := newTestRedisBackend(t)
+
+	windows := []ratelimiter.Window{{Limit: 20, Count: 20, Duration: time.Second}, {Limit: 100, Count: 30, Duration: 2 * time.Minute}}
+	if err := backend.Update("NA1", windows); err != nil {
+		t.Fatalf("Update() = %v", err)
+	}
+
+	// The 2 minute window is no longer limited, so its count is deleted
+	if err := backend.Update("NA1", []ratelimiter.Window{{Limit: 20, Count: 20, Duration: time.Second}}); err != nil {
+		t.Fatalf("Update() = %v", err)
+	}
+
+	if server.Exists("riot-ratelimit:NA1:count:120000") {
+		t.Error("Update() kept the count of a window that is no longer limited")
+	}
+
+	if count, _ := server.Get("riot-ratelimit:NA1:count:1000"); count != "20" {
+		t.Errorf("1 second window count = %q, want 20", count)
+	}
+
+	// A window of the same duration limited again starts from Riot's count
+	if err := backend.Update("NA1", []ratelimiter.Window{{Limit: 100, Count: 1, Duration: 2 * time.Minute}}); err != nil {
+		t.Fatalf("Update() = %v", err)
+	}
+
+	if state, _ := backend.State("NA1"); len(state.Windows) != 1 || state.Windows[0].Count != 1 {
+		t.Errorf("State().Windows = %+v, want the 2 minute window with a count of 1", state.Windows)
+	}
+}
+
+func TestRedisBackendClock(t *testing.T) {
+	backend, server := newTestRedisBackend(t)
+
+	// The fake clock is behind the real time, so blocks are only stored if they are timed by it
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	backend.SetClock(fake)
+
+	until := fake.Now().Add(time.Minute)
+	if err := backend.Block("NA1", until); err != nil {
+		t.Fatalf("Block() = %v", err)
+	}
+
+	if ttl := server.TTL("riot-ratelimit:NA1:blocked"); ttl != time.Minute {
+		t.Errorf("TTL of the block = %v, want 1m0s", ttl)
+	}
+
+	if err := backend.Obtain(context.Background(), "NA1", []ratelimiter.Window{{Limit: 1, Duration: time.Minute}}); err != nil {
+		t.Fatalf("Obtain() = %v", err)
+	}
+
+	state, err := backend.State("NA1")
+	if err != nil || len(state.Windows) != 1 || !state.BlockedUntil.Equal(until) {
+		t.Fatalf("State() = %+v, %v, want 1 window blocked until %v", state, err, until)
+	}
+
+	if resetAt := state.Windows[0].ResetAt; resetAt.After(until) || resetAt.Before(until.Add(-time.Second)) {
+		t.Errorf("ResetAt = %v, want about a minute after the fake clock's time", resetAt)
+	}
+}
@@ -0,0 +1,121 @@
+package redistest
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kinveil/Riot-API-Golang/apiclient/cache"
+	"github.com/alicebob/miniredis/v2"
+)
+
+// cachedValue is encoded as JSON.
+type cachedValue struct {
+	Name  string
+	Level int
+}
+
+// binaryValue is encoded with its MarshalBinary method, which prefixes its JSON.
+type binaryValue struct {
+	Name string
+}
+
+func (v binaryValue) MarshalBinary() ([]byte, error) {
+	data, err := json.Marshal(v)
+	return append([]byte("binary:"), data...), err
+}
+
+func (v *binaryValue) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal([]byte(strings.TrimPrefix(string(data), "binary:")), v)
+}
+
+// newTestRedis returns a Redis cache connected to an in-process Redis server.
+func newTestRedis(t *testing.T) (*cache.Redis, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	c := cache.NewRedis(cache.RedisOptions{Addr: server.Addr()})
+	t.Cleanup(func() {
+		c.Close()
+	})
+
+	return c, server
+}
+
+func TestRedisGetAndSet(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedis(t)
+
+	var got cachedValue
+	if _, ok, err := c.Get(ctx, "a", &got); ok || err != nil {
+		t.Fatalf("Get(a) before Set() = %v, %v, want a miss", ok, err)
+	}
+
+	before := time.Now().Truncate(time.Millisecond)
+	if err := c.Set(ctx, "a", &cachedValue{Name: "a", Level: 3}, time.Minute); err != nil {
+		t.Fatalf("Set() = %v", err)
+	}
+
+	storedAt, ok, err := c.Get(ctx, "a", &got)
+	if !ok || err != nil || got != (cachedValue{Name: "a", Level: 3}) {
+		t.Fatalf("Get(a) = %+v, %v, %v, want the stored value", got, ok, err)
+	}
+
+	if storedAt.Before(before) || storedAt.After(time.Now()) {
+		t.Errorf("Get(a) stored at %v, want the time of Set()", storedAt)
+	}
+
+	// Values are stored under the prefix and expire with their TTL
+	if ttl := server.TTL("riot-cache:a"); ttl != time.Minute {
+		t.Errorf("TTL of riot-cache:a = %v, want 1m0s", ttl)
+	}
+
+	server.FastForward(time.Minute)
+
+	if _, ok, err := c.Get(ctx, "a", &got); ok || err != nil {
+		t.Errorf("Get(a) after its TTL = %v, %v, want a miss", ok, err)
+	}
+
+	// A value that would expire right away is not stored
+	if err := c.Set(ctx, "b", &cachedValue{Name: "b"}, time.Microsecond); err != nil || server.Exists("riot-cache:b") {
+		t.Errorf("Set() with a TTL under 1ms = %v, want nothing stored", err)
+	}
+}
+
+func TestRedisBinaryMarshaler(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedis(t)
+
+	if err := c.Set(ctx, "a", binaryValue{Name: "a"}, time.Minute); err != nil {
+		t.Fatalf("Set() = %v", err)
+	}
+
+	if data, _ := server.Get("riot-cache:a"); !strings.HasSuffix(data, "\nbinary:{\"Name\":\"a\"}") {
+		t.Errorf("stored %q, want the value's MarshalBinary encoding", data)
+	}
+
+	var got binaryValue
+	if _, ok, err := c.Get(ctx, "a", &got); !ok || err != nil || got.Name != "a" {
+		t.Errorf("Get(a) = %+v, %v, %v, want a", got, ok, err)
+	}
+}
+
+func TestRedisGetLeavesDestOnError(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedis(t)
+
+	for _, data := range []string{
+		`no time`,
+		"now\n{}",
+		"1700000000000\n{\"Name\": \"partial\", \"Level\": \"3\"}",
+	} {
+		server.Set("riot-cache:a", data)
+
+		got := cachedValue{Name: "unchanged"}
+		if _, ok, err := c.Get(ctx, "a", &got); ok || err == nil || got != (cachedValue{Name: "unchanged"}) {
+			t.Errorf("Get(a) of %q = %+v, %v, %v, want an error with dest unchanged", data, got, ok, err)
+		}
+	}
+}
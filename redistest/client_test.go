@@ -0,0 +1,95 @@
+package redistest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Kinveil/Riot-API-Golang/internal/redis"
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestClientDo(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	ctx := context.Background()
+
+	tests := []struct {
+		args   []interface{}
+		expect interface{}
+	}{
+		{[]interface{}{"SET", "key", "value"}, "OK"},
+		{[]interface{}{"GET", "key"}, "value"},
+		{[]interface{}{"GET", "missing"}, nil},
+		{[]interface{}{"INCRBY", "counter", 5}, int64(5)},
+		{[]interface{}{"INCRBY", "counter", int64(2)}, int64(7)},
+		{[]interface{}{"SET", "binary", []byte("a\r\nb\x00")}, "OK"},
+		{[]interface{}{"GET", "binary"}, "a\r\nb\x00"},
+		{[]interface{}{"RPUSH", "list", "a", "b"}, int64(2)},
+		{[]interface{}{"LRANGE", "list", 0, -1}, []interface{}{"a", "b"}},
+		{[]interface{}{"EVAL", "return {1, 'x', {2}}", 0}, []interface{}{int64(1), "x", []interface{}{int64(2)}}},
+	}
+
+	for _, test := range tests {
+		reply, err := client.Do(ctx, test.args...)
+		if err != nil {
+			t.Fatalf("Do(%v) = %v", test.args, err)
+		}
+
+		if !reflect.DeepEqual(reply, test.expect) {
+			t.Errorf("Do(%v) = %#v, want %#v", test.args, reply, test.expect)
+		}
+	}
+
+	// Error replies are returned as a redis.Error, and the connection is still usable
+	_, err := client.Do(ctx, "INCR", "key")
+	var replyErr redis.Error
+	if !errors.As(err, &replyErr) {
+		t.Fatalf("Do(INCR of a string) = %v, want an redis.Error", err)
+	}
+
+	if reply, err := client.Do(ctx, "GET", "key"); err != nil || reply != "value" {
+		t.Errorf("Do(GET) after an error reply = %v, %v", reply, err)
+	}
+}
+
+func TestClientAuthAndSelect(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+
+	client := redis.NewClient(redis.Options{Addr: server.Addr(), Password: "secret", DB: 2})
+	defer client.Close()
+
+	if _, err := client.Do(context.Background(), "SET", "key", "value"); err != nil {
+		t.Fatalf("Do(SET) = %v", err)
+	}
+
+	if value, err := server.DB(2).Get("key"); err != nil || value != "value" {
+		t.Errorf("value in DB 2 = %q, %v, want %q", value, err, "value")
+	}
+
+	unauthenticated := redis.NewClient(redis.Options{Addr: server.Addr()})
+	defer unauthenticated.Close()
+
+	if _, err := unauthenticated.Do(context.Background(), "GET", "key"); err == nil {
+		t.Error("Do() without the password succeeded")
+	}
+}
+
+func TestClientClosed(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(redis.Options{Addr: server.Addr()})
+
+	if _, err := client.Do(context.Background(), "PING"); err != nil {
+		t.Fatalf("Do(PING) = %v", err)
+	}
+
+	client.Close()
+
+	if _, err := client.Do(context.Background(), "PING"); !errors.Is(err, redis.ErrClosed) {
+		t.Errorf("Do() after Close() = %v, want %v", err, redis.ErrClosed)
+	}
+}
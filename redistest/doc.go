@@ -0,0 +1,6 @@
+// Package redistest tests the Redis client, cache and rate limit backend of this module against
+// an in-process Redis server. It is a separate module so that the server it uses, miniredis, is
+// not a requirement of the modules that use the Riot API client. Run its tests from this directory:
+//
+//	go test ./...
+package redistest
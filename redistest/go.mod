@@ -0,0 +1,15 @@
+module github.com/Kinveil/Riot-API-Golang/redistest
+
+go 1.19
+
+require (
+	github.com/Kinveil/Riot-API-Golang v0.0.0
+	github.com/alicebob/miniredis/v2 v2.31.0
+)
+
+require (
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
+	github.com/yuin/gopher-lua v1.1.0 // indirect
+)
+
+replace github.com/Kinveil/Riot-API-Golang => ../